@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// 定义错误
+var (
+	ErrCacheMiss    = errors.New("缓存不存在")
+	ErrTagsDisabled = errors.New("缓存标签未启用")
+)
+
+// Store 缓存接口，值以JSON序列化存储
+type Store interface {
+	// Get 读取缓存并解码到dest，缓存不存在时返回ErrCacheMiss
+	Get(ctx context.Context, key string, dest interface{}) error
+	// Set 写入缓存，ttl为0时使用默认过期时间，tags用于批量失效
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error
+	// Delete 删除缓存
+	Delete(ctx context.Context, keys ...string) error
+	// Remember 读取缓存，不存在时调用fn生成并写入缓存
+	// 同一个键的并发未命中只会调用一次fn，避免缓存击穿
+	Remember(ctx context.Context, key string, ttl time.Duration, dest interface{}, fn func() (interface{}, error), tags ...string) error
+	// FlushTags 删除带有任一指定标签的所有缓存
+	FlushTags(ctx context.Context, tags ...string) error
+	// Increment 原子地将计数加1并返回新值，计数不存在时从0开始并设置过期时间ttl
+	// 之后的递增不会延长过期时间，可用于固定窗口计数
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// Driver 缓存驱动，负责存取已序列化的字节
+// 驱动收到的键和标签都已添加前缀
+type Driver interface {
+	// Get 读取缓存，不存在时返回ErrCacheMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set 写入缓存并记录标签
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+	// Delete 删除缓存
+	Delete(ctx context.Context, keys ...string) error
+	// FlushTags 删除带有任一指定标签的所有缓存
+	FlushTags(ctx context.Context, tags ...string) error
+	// Increment 原子地递增计数，计数不存在时设置过期时间
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// Cache 在驱动之上实现Store，负责序列化、键前缀、默认过期时间和防击穿
+type Cache struct {
+	driver      Driver
+	prefix      string
+	ttl         time.Duration
+	tagsEnabled bool
+	group       singleflight.Group
+}
+
+// New 创建缓存实例
+func New(driver Driver, prefix string, ttl time.Duration, tagsEnabled bool) *Cache {
+	return &Cache{
+		driver:      driver,
+		prefix:      prefix,
+		ttl:         ttl,
+		tagsEnabled: tagsEnabled,
+	}
+}
+
+// Get 读取缓存并解码到dest
+func (c *Cache) Get(ctx context.Context, key string, dest interface{}) error {
+	data, err := c.driver.Get(ctx, c.key(key))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// Set 写入缓存
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.driver.Set(ctx, c.key(key), data, c.expiration(ttl), c.tagKeys(tags))
+}
+
+// Delete 删除缓存
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.key(key)
+	}
+	return c.driver.Delete(ctx, prefixed...)
+}
+
+// Remember 读取缓存，不存在时调用fn生成并写入缓存
+func (c *Cache) Remember(ctx context.Context, key string, ttl time.Duration, dest interface{}, fn func() (interface{}, error), tags ...string) error {
+	err := c.Get(ctx, key, dest)
+	if err == nil {
+		return nil
+	}
+	// 缓存不可用时降级为直接调用fn
+	if !errors.Is(err, ErrCacheMiss) {
+		log.Printf("读取缓存失败: %s: %v", key, err)
+	}
+
+	data, err, _ := c.group.Do(key, func() (interface{}, error) {
+		value, err := fn()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.driver.Set(ctx, c.key(key), data, c.expiration(ttl), c.tagKeys(tags)); err != nil {
+			log.Printf("写入缓存失败: %s: %v", key, err)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data.([]byte), dest)
+}
+
+// FlushTags 删除带有任一指定标签的所有缓存
+func (c *Cache) FlushTags(ctx context.Context, tags ...string) error {
+	if !c.tagsEnabled {
+		return ErrTagsDisabled
+	}
+	return c.driver.FlushTags(ctx, c.tagKeys(tags)...)
+}
+
+// Increment 原子地将计数加1并返回新值，计数可以通过Get读取为整数
+func (c *Cache) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.driver.Increment(ctx, c.key(key), c.expiration(ttl))
+}
+
+// key 为缓存键添加前缀
+func (c *Cache) key(key string) string {
+	if c.prefix == "" {
+		return key
+	}
+	return c.prefix + ":" + key
+}
+
+// tagKeys 为标签添加前缀，未启用标签时返回nil
+func (c *Cache) tagKeys(tags []string) []string {
+	if !c.tagsEnabled || len(tags) == 0 {
+		return nil
+	}
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = c.key("tag:" + tag)
+	}
+	return keys
+}
+
+// expiration 返回实际使用的过期时间
+func (c *Cache) expiration(ttl time.Duration) time.Duration {
+	if ttl > 0 {
+		return ttl
+	}
+	return c.ttl
+}
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryDriver 进程内LRU缓存驱动，支持过期时间和标签
+// 缓存只在当前进程有效，多副本部署时应使用Redis驱动
+type MemoryDriver struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	tags     map[string]map[string]struct{}
+}
+
+// memoryEntry 缓存条目
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	tags      []string
+}
+
+// NewMemoryDriver 创建内存缓存驱动，capacity为最大条目数，超出时淘汰最久未使用的条目
+func NewMemoryDriver(capacity int) *MemoryDriver {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &MemoryDriver{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		tags:     make(map[string]map[string]struct{}),
+	}
+}
+
+// Get 读取缓存
+func (d *MemoryDriver) Get(ctx context.Context, key string) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	elem, ok := d.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		d.remove(elem)
+		return nil, ErrCacheMiss
+	}
+
+	d.ll.MoveToFront(elem)
+	return entry.value, nil
+}
+
+// Set 写入缓存
+func (d *MemoryDriver) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if elem, ok := d.items[key]; ok {
+		d.remove(elem)
+	}
+
+	entry := &memoryEntry{
+		key:   key,
+		value: value,
+		tags:  tags,
+	}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	d.items[key] = d.ll.PushFront(entry)
+
+	for _, tag := range tags {
+		if d.tags[tag] == nil {
+			d.tags[tag] = make(map[string]struct{})
+		}
+		d.tags[tag][key] = struct{}{}
+	}
+
+	for d.ll.Len() > d.capacity {
+		d.remove(d.ll.Back())
+	}
+	return nil
+}
+
+// Delete 删除缓存
+func (d *MemoryDriver) Delete(ctx context.Context, keys ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := d.items[key]; ok {
+			d.remove(elem)
+		}
+	}
+	return nil
+}
+
+// FlushTags 删除带有任一指定标签的所有缓存
+func (d *MemoryDriver) FlushTags(ctx context.Context, tags ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range d.tags[tag] {
+			if elem, ok := d.items[key]; ok {
+				d.remove(elem)
+			}
+		}
+		delete(d.tags, tag)
+	}
+	return nil
+}
+
+// Increment 原子地递增计数，已过期或不存在的计数从0开始
+func (d *MemoryDriver) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if elem, ok := d.items[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		if entry.expiresAt.IsZero() || now.Before(entry.expiresAt) {
+			n, err := strconv.ParseInt(string(entry.value), 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			n++
+			entry.value = []byte(strconv.FormatInt(n, 10))
+			d.ll.MoveToFront(elem)
+			return n, nil
+		}
+		d.remove(elem)
+	}
+
+	entry := &memoryEntry{
+		key:   key,
+		value: []byte("1"),
+	}
+	if ttl > 0 {
+		entry.expiresAt = now.Add(ttl)
+	}
+	d.items[key] = d.ll.PushFront(entry)
+
+	for d.ll.Len() > d.capacity {
+		d.remove(d.ll.Back())
+	}
+	return 1, nil
+}
+
+// remove 删除条目并清理标签索引，调用方需持有锁
+func (d *MemoryDriver) remove(elem *list.Element) {
+	entry := d.ll.Remove(elem).(*memoryEntry)
+	delete(d.items, entry.key)
+
+	for _, tag := range entry.tags {
+		if keys, ok := d.tags[tag]; ok {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(d.tags, tag)
+			}
+		}
+	}
+}
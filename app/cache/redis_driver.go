@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// flushTagScript 原子地删除标签集合及其中的所有键，避免删除期间新写入的键脱离标签
+var flushTagScript = redis.NewScript(`
+local keys = redis.call('SMEMBERS', KEYS[1])
+for i = 1, #keys, 500 do
+	redis.call('DEL', unpack(keys, i, math.min(i + 499, #keys)))
+end
+redis.call('DEL', KEYS[1])
+return #keys
+`)
+
+// incrementScript 递增计数，只在计数新建时设置过期时间
+var incrementScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// RedisDriver Redis缓存驱动
+// 标签以集合形式保存其下的缓存键，失效时删除集合中的所有键
+type RedisDriver struct {
+	client redis.UniversalClient
+}
+
+// NewRedisDriver 创建Redis缓存驱动
+func NewRedisDriver(client redis.UniversalClient) *RedisDriver {
+	return &RedisDriver{client: client}
+}
+
+// Get 读取缓存
+func (d *RedisDriver) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := d.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return data, err
+}
+
+// Set 写入缓存并将键加入标签集合
+func (d *RedisDriver) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	_, err := d.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, value, ttl)
+		// 标签集合不设置过期时间，集合中已过期的键在失效时删除不会产生影响
+		for _, tag := range tags {
+			pipe.SAdd(ctx, tag, key)
+		}
+		return nil
+	})
+	return err
+}
+
+// Delete 删除缓存
+func (d *RedisDriver) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return d.client.Del(ctx, keys...).Err()
+}
+
+// FlushTags 删除带有任一指定标签的所有缓存
+func (d *RedisDriver) FlushTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		if err := flushTagScript.Run(ctx, d.client, []string{tag}).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Increment 原子地递增计数
+func (d *RedisDriver) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrementScript.Run(ctx, d.client, []string{key}, ttl.Milliseconds()).Int64()
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/NextEraAbyss/fiber-template/app/middleware"
+	"github.com/NextEraAbyss/fiber-template/app/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyController API密钥控制器
+type APIKeyController struct {
+	apiKeyService *service.APIKeyService
+}
+
+// NewAPIKeyController 创建新的API密钥控制器实例
+func NewAPIKeyController() *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: service.NewAPIKeyService(),
+	}
+}
+
+// GetAPIKeys 获取当前用户的API密钥列表
+// @Summary 获取API密钥列表
+// @Description 获取当前用户的所有API密钥，不包含密钥明文
+// @Tags API密钥
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/api-keys [get]
+func (c *APIKeyController) GetAPIKeys(ctx *fiber.Ctx) error {
+	keys, err := c.apiKeyService.List(ctx.UserContext(), middleware.CurrentUser(ctx).ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"api_keys": keys,
+	})
+}
+
+// CreateAPIKey 创建API密钥
+// @Summary 创建API密钥
+// @Description 创建API密钥，返回的key只显示一次，请求时通过X-API-Key请求头传递
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body service.CreateAPIKeyParams true "API密钥参数"
+// @Success 201 {object} fiber.Map
+// @Router /api/v1/api-keys [post]
+func (c *APIKeyController) CreateAPIKey(ctx *fiber.Ctx) error {
+	var params service.CreateAPIKeyParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	key, plain, err := c.apiKeyService.Create(ctx.UserContext(), middleware.CurrentUser(ctx), params, clientInfo(ctx))
+	if err != nil {
+		switch err {
+		case service.ErrInvalidExpiry:
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case service.ErrAPIKeyLimit:
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"key":     plain,
+		"api_key": key,
+	})
+}
+
+// UpdateAPIKey 修改API密钥
+// @Summary 修改API密钥
+// @Description 修改API密钥的名称和权限
+// @Tags API密钥
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API密钥ID"
+// @Param body body service.UpdateAPIKeyParams true "API密钥参数"
+// @Success 200 {object} model.APIKey
+// @Router /api/v1/api-keys/{id} [put]
+func (c *APIKeyController) UpdateAPIKey(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的API密钥ID")
+	}
+
+	var params service.UpdateAPIKeyParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	key, err := c.apiKeyService.Update(ctx.UserContext(), middleware.CurrentUser(ctx).ID, id, params)
+	if err != nil {
+		if err == service.ErrAPIKeyNotFound {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(key)
+}
+
+// DeleteAPIKey 吊销API密钥
+// @Summary 吊销API密钥
+// @Description 删除API密钥，使用该密钥的请求会立即被拒绝
+// @Tags API密钥
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "API密钥ID"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/api-keys/{id} [delete]
+func (c *APIKeyController) DeleteAPIKey(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的API密钥ID")
+	}
+
+	err = c.apiKeyService.Delete(ctx.UserContext(), middleware.CurrentUser(ctx).ID, id, clientInfo(ctx))
+	if err != nil {
+		if err == service.ErrAPIKeyNotFound {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "API密钥已吊销",
+	})
+}
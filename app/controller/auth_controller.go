@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/NextEraAbyss/fiber-template/app/middleware"
+	"github.com/NextEraAbyss/fiber-template/app/resource"
+	"github.com/NextEraAbyss/fiber-template/app/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuthController 认证控制器
+type AuthController struct {
+	authService         *service.AuthService
+	passwordService     *service.PasswordService
+	verificationService *service.VerificationService
+}
+
+// NewAuthController 创建新的认证控制器实例
+func NewAuthController() *AuthController {
+	return &AuthController{
+		authService:         service.NewAuthService(),
+		passwordService:     service.NewPasswordService(),
+		verificationService: service.NewVerificationService(),
+	}
+}
+
+// Login 用户登录
+// @Summary 用户登录
+// @Description 使用用户名或邮箱和密码登录，返回JWT令牌。启用两步验证的用户返回challenge_token，需调用 /auth/2fa/verify 完成登录。连续失败会临时锁定账号（423），同一IP失败过多会被限流（429）
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body service.LoginParams true "登录参数"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/auth/login [post]
+func (c *AuthController) Login(ctx *fiber.Ctx) error {
+	var params service.LoginParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	result, err := c.authService.Login(ctx.UserContext(), params, clientInfo(ctx))
+	if err != nil {
+		var limited *service.RateLimitError
+		var locked *service.AccountLockedError
+		switch {
+		case errors.As(err, &limited):
+			return tooManyRequests(ctx, limited)
+		case errors.As(err, &locked):
+			return accountLocked(ctx, locked)
+		case err == service.ErrInvalidCredentials:
+			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+		case err == service.ErrUserDisabled:
+			return fiber.NewError(fiber.StatusForbidden, err.Error())
+		}
+		return err
+	}
+
+	if result.ChallengeToken != "" {
+		return ctx.JSON(fiber.Map{
+			"two_factor_required": true,
+			"challenge_token":     result.ChallengeToken,
+		})
+	}
+	return ctx.JSON(fiber.Map{
+		"token":      result.Token,
+		"token_type": "Bearer",
+		"user":       resource.User.Serialize(result.User),
+	})
+}
+
+// ForgotPassword 忘记密码
+// @Summary 忘记密码
+// @Description 向邮箱发送重置密码链接，无论邮箱是否已注册都返回相同的响应
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body service.ForgotPasswordParams true "忘记密码参数"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/auth/password/forgot [post]
+func (c *AuthController) ForgotPassword(ctx *fiber.Ctx) error {
+	var params service.ForgotPasswordParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	if err := c.passwordService.ForgotPassword(ctx.UserContext(), params); err != nil {
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "如果该邮箱已注册，重置密码的链接已发送到该邮箱",
+	})
+}
+
+// ResetPassword 重置密码
+// @Summary 重置密码
+// @Description 使用邮件中的令牌设置新密码，成功后该用户所有已登录的会话失效
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body service.ResetPasswordParams true "重置密码参数"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/auth/password/reset [post]
+func (c *AuthController) ResetPassword(ctx *fiber.Ctx) error {
+	var params service.ResetPasswordParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	err := c.passwordService.ResetPassword(ctx.UserContext(), params)
+	if err != nil {
+		var weak *service.WeakPasswordError
+		switch {
+		case errors.As(err, &weak):
+			return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+		case err == service.ErrInvalidToken:
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "密码已重置，请使用新密码登录",
+	})
+}
+
+// Register 用户注册
+// @Summary 用户注册
+// @Description 注册新用户并向邮箱发送验证链接，返回JWT令牌
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body service.RegisterParams true "注册参数"
+// @Success 201 {object} fiber.Map
+// @Router /api/v1/auth/register [post]
+func (c *AuthController) Register(ctx *fiber.Ctx) error {
+	var params service.RegisterParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	token, user, err := c.authService.Register(ctx.UserContext(), params, clientInfo(ctx))
+	if err != nil {
+		var weak *service.WeakPasswordError
+		switch {
+		case errors.As(err, &weak):
+			return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, service.ErrInvalidUsername), err == service.ErrInvalidEmail:
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case err == service.ErrUserExists:
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"token":      token,
+		"token_type": "Bearer",
+		"user":       resource.User.Serialize(user),
+	})
+}
+
+// VerifyEmail 验证邮箱
+// @Summary 验证邮箱
+// @Description 使用邮件中的令牌确认邮箱，修改邮箱时验证通过后才会替换为新邮箱
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param body body service.VerifyEmailParams true "验证邮箱参数"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/auth/email/verify [post]
+func (c *AuthController) VerifyEmail(ctx *fiber.Ctx) error {
+	var params service.VerifyEmailParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	user, err := c.verificationService.Verify(ctx.UserContext(), params)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidToken:
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case service.ErrEmailTaken:
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "邮箱验证成功",
+		"user":    resource.User.Serialize(user),
+	})
+}
+
+// ResendVerification 重新发送验证邮件
+// @Summary 重新发送验证邮件
+// @Description 向当前用户的邮箱重新发送验证链接，发送间隔受限
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/auth/email/resend [post]
+func (c *AuthController) ResendVerification(ctx *fiber.Ctx) error {
+	err := c.verificationService.Resend(ctx.UserContext(), middleware.CurrentUser(ctx))
+	if err != nil {
+		var limited *service.RateLimitError
+		switch {
+		case errors.As(err, &limited):
+			return tooManyRequests(ctx, limited)
+		case err == service.ErrEmailAlreadyVerified:
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "验证邮件已发送",
+	})
+}
+
+// ChangeEmail 修改邮箱
+// @Summary 修改邮箱
+// @Description 校验当前密码后向新邮箱发送验证链接，验证通过后邮箱才会被修改
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body service.ChangeEmailParams true "修改邮箱参数"
+// @Success 202 {object} fiber.Map
+// @Router /api/v1/auth/email [put]
+func (c *AuthController) ChangeEmail(ctx *fiber.Ctx) error {
+	var params service.ChangeEmailParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	err := c.verificationService.ChangeEmail(ctx.UserContext(), middleware.CurrentUser(ctx), params)
+	if err != nil {
+		var limited *service.RateLimitError
+		switch {
+		case errors.As(err, &limited):
+			return tooManyRequests(ctx, limited)
+		case err == service.ErrInvalidCredentials:
+			return fiber.NewError(fiber.StatusUnauthorized, "密码错误")
+		case err == service.ErrInvalidEmail, err == service.ErrEmailAlreadyVerified:
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		case err == service.ErrEmailTaken:
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		return err
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "验证邮件已发送到新邮箱，验证后生效",
+	})
+}
@@ -0,0 +1,588 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/filter"
+	"github.com/NextEraAbyss/fiber-template/app/middleware"
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/pagination"
+	"github.com/NextEraAbyss/fiber-template/app/resource"
+	"github.com/NextEraAbyss/fiber-template/app/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// FileController 文件控制器
+type FileController struct {
+	fileService     *service.FileService
+	uploadService   *service.UploadSessionService
+	downloadService *service.DownloadService
+}
+
+// NewFileController 创建新的文件控制器实例
+func NewFileController() *FileController {
+	return &FileController{
+		fileService:     service.NewFileService(),
+		uploadService:   service.NewUploadSessionService(),
+		downloadService: service.NewDownloadService(),
+	}
+}
+
+// Upload 上传文件
+// @Summary 上传文件
+// @Description 以multipart/form-data上传一个或多个文件，文件类型根据内容识别，大小、类型和数量受上传配置限制
+// @Tags 文件
+// @Accept mpfd
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "文件，可重复"
+// @Success 201 {object} fiber.Map
+// @Router /api/v1/files [post]
+func (c *FileController) Upload(ctx *fiber.Ctx) error {
+	body, boundary, err := multipartBody(ctx)
+	if err != nil {
+		return err
+	}
+
+	files, err := c.fileService.Upload(ctx.UserContext(), middleware.CurrentUser(ctx), body, boundary)
+	if err != nil {
+		return fileError(err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"files": resource.File.SerializeMany(files),
+	})
+}
+
+// GetFiles 获取当前用户的文件列表
+// @Summary 获取文件列表
+// @Description 获取当前用户上传的文件
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param page_size query int false "每页数量，1到100，默认20"
+// @Param sort query string false "排序字段：id、name、size、created_at，前缀-表示降序，默认-id"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor"
+// @Param page query int false "页码，指定时使用偏移分页，不能与cursor同时使用"
+// @Param filter[name][like] query string false "文件名包含，可筛选字段：name、mime_type、size、created_at"
+// @Param fields query string false "逗号分隔的输出字段，默认输出全部字段"
+// @Success 200 {object} fiber.Map
+// @Header 200 {string} Link "首页和下一页的地址"
+// @Router /api/v1/files [get]
+func (c *FileController) GetFiles(ctx *fiber.Ctx) error {
+	page, err := pagination.Parse(ctx, service.FileListOptions)
+	if err != nil {
+		return err
+	}
+
+	f, err := filter.Parse(ctx, service.FileFilters)
+	if err != nil {
+		return err
+	}
+
+	sel, err := resource.File.Parse(ctx)
+	if err != nil {
+		return err
+	}
+
+	files, result, err := c.fileService.List(ctx.UserContext(), middleware.CurrentUser(ctx).ID, page, f.Scope, sel.Scope)
+	if err != nil {
+		return err
+	}
+	out, err := sel.Many(ctx, files)
+	if err != nil {
+		return err
+	}
+
+	pagination.SetLinks(ctx, page, result)
+	return ctx.JSON(fiber.Map{
+		"files":      out,
+		"pagination": result,
+	})
+}
+
+// GetFile 获取文件信息
+// @Summary 获取文件信息
+// @Description 获取当前用户上传的单个文件信息
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Success 200 {object} model.File
+// @Router /api/v1/files/{id} [get]
+func (c *FileController) GetFile(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的文件ID")
+	}
+
+	file, err := c.fileService.Get(ctx.UserContext(), middleware.CurrentUser(ctx).ID, id)
+	if err != nil {
+		return fileError(err)
+	}
+	return ctx.JSON(resource.File.Serialize(file))
+}
+
+// Download 下载文件
+// @Summary 下载文件
+// @Description 下载当前用户上传的文件内容，支持Range请求和条件请求
+// @Tags 文件
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Success 200 {file} file
+// @Success 206 {file} file
+// @Router /api/v1/files/{id}/download [get]
+func (c *FileController) Download(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的文件ID")
+	}
+
+	file, err := c.fileService.Get(ctx.UserContext(), middleware.CurrentUser(ctx).ID, id)
+	if err != nil {
+		return fileError(err)
+	}
+	return c.sendFile(ctx, file, service.DispositionAttachment, file.Name, "private, no-cache")
+}
+
+// CreateDownloadURL 创建签名下载链接
+// @Summary 创建签名下载链接
+// @Description 为当前用户的文件创建带签名的临时下载地址，持有地址即可下载，无需认证头，可用于<img>等无法携带认证信息的场景。可以设置有效期、是否只能使用一次、以附件还是内联方式返回以及下载时的文件名
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Param params body service.CreateDownloadURLParams false "链接选项"
+// @Success 201 {object} service.DownloadURL
+// @Router /api/v1/files/{id}/url [post]
+func (c *FileController) CreateDownloadURL(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的文件ID")
+	}
+	var params service.CreateDownloadURLParams
+	if err := limitBody(ctx); err != nil {
+		return err
+	}
+	if len(ctx.Body()) > 0 {
+		if err := parseBody(ctx, &params); err != nil {
+			return err
+		}
+	}
+
+	url, err := c.downloadService.CreateURL(ctx.UserContext(), middleware.CurrentUser(ctx).ID, id, params)
+	if err != nil {
+		return fileError(err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(url)
+}
+
+// SignedDownload 通过签名链接下载文件
+// @Summary 通过签名链接下载文件
+// @Description 校验签名和有效期后返回文件内容，支持Range请求和条件请求；一次性链接在第一次GET请求后失效
+// @Tags 文件
+// @Produce octet-stream
+// @Param id path int true "文件ID"
+// @Param expires query int true "过期时间"
+// @Param disposition query string true "下载方式"
+// @Param filename query string false "文件名"
+// @Param nonce query string false "一次性链接的随机值"
+// @Param signature query string true "签名"
+// @Success 200 {file} file
+// @Success 206 {file} file
+// @Router /api/v1/downloads/{id} [get]
+func (c *FileController) SignedDownload(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return fileError(service.ErrDownloadLinkInvalid)
+	}
+	expires, err := strconv.ParseInt(ctx.Query("expires"), 10, 64)
+	if err != nil {
+		return fileError(service.ErrDownloadLinkInvalid)
+	}
+	link := service.DownloadLink{
+		FileID:      id,
+		Expires:     expires,
+		Disposition: ctx.Query("disposition"),
+		Filename:    ctx.Query("filename"),
+		Nonce:       ctx.Query("nonce"),
+		Signature:   ctx.Query("signature"),
+	}
+
+	// HEAD请求不返回内容，不消耗一次性链接
+	file, err := c.downloadService.Resolve(ctx.UserContext(), link, ctx.Method() != fiber.MethodHead)
+	if err != nil {
+		return fileError(err)
+	}
+
+	filename := link.Filename
+	if filename == "" {
+		filename = file.Name
+	}
+	// 缓存时间不超过链接的有效期，一次性链接不允许缓存
+	cacheControl := "no-store"
+	if link.Nonce == "" {
+		cacheControl = fmt.Sprintf("private, max-age=%d", int(time.Until(time.Unix(expires, 0)).Seconds()))
+	}
+	// 避免链接通过Referer泄露给内联内容引用的其他站点
+	ctx.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+	return c.sendFile(ctx, file, link.Disposition, filename, cacheControl)
+}
+
+// sendFile 发送文件内容，支持条件请求和单个范围的Range请求
+// 文件内容不可变，以内容哈希作为ETag
+func (c *FileController) sendFile(ctx *fiber.Ctx, file *model.File, disposition, filename, cacheControl string) error {
+	etag := `"` + file.Checksum + `"`
+	lastModified := file.CreatedAt.UTC().Truncate(time.Second)
+	ctx.Set(fiber.HeaderCacheControl, cacheControl)
+	ctx.Set(fiber.HeaderETag, etag)
+	ctx.Set(fiber.HeaderLastModified, lastModified.Format(http.TimeFormat))
+	ctx.Set(fiber.HeaderAcceptRanges, "bytes")
+
+	if inm := ctx.Get(fiber.HeaderIfNoneMatch); inm != "" {
+		if etagMatches(inm, etag) {
+			return ctx.SendStatus(fiber.StatusNotModified)
+		}
+	} else if t, err := http.ParseTime(ctx.Get(fiber.HeaderIfModifiedSince)); err == nil && !lastModified.After(t) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	offset, length := int64(0), file.Size
+	partial := false
+	if header := ctx.Get(fiber.HeaderRange); header != "" && ifRangeMatches(ctx.Get(fiber.HeaderIfRange), etag, lastModified) {
+		start, n, ok := parseRange(header, file.Size)
+		if !ok {
+			ctx.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", file.Size))
+			return fiber.NewError(fiber.StatusRequestedRangeNotSatisfiable, "请求的范围无效")
+		}
+		// 无法解析或包含多个范围时忽略Range，返回完整内容
+		if n > 0 {
+			offset, length, partial = start, n, true
+		}
+	}
+
+	var content io.ReadCloser
+	var err error
+	if partial {
+		content, err = c.fileService.OpenRange(ctx.UserContext(), file, offset, length)
+	} else {
+		content, err = c.fileService.Open(ctx.UserContext(), file)
+	}
+	if err != nil {
+		return fileError(err)
+	}
+
+	// HTML、XML等可以执行脚本的类型只能作为附件下载
+	if disposition == service.DispositionInline && scriptable(file.MimeType) {
+		disposition = service.DispositionAttachment
+	}
+	ctx.Set(fiber.HeaderContentType, file.MimeType)
+	ctx.Set(fiber.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": filename}))
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	if disposition == service.DispositionInline {
+		// 内联显示的用户文件不允许加载其他资源
+		ctx.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; img-src 'self'; media-src 'self'; style-src 'unsafe-inline'")
+	}
+	if partial {
+		ctx.Status(fiber.StatusPartialContent)
+		ctx.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, file.Size))
+	}
+	return ctx.SendStream(content, int(length))
+}
+
+// DeleteFile 删除文件
+// @Summary 删除文件
+// @Description 删除当前用户上传的文件
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "文件ID"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/files/{id} [delete]
+func (c *FileController) DeleteFile(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的文件ID")
+	}
+
+	if err := c.fileService.Delete(ctx.UserContext(), middleware.CurrentUser(ctx).ID, id); err != nil {
+		return fileError(err)
+	}
+	return ctx.JSON(fiber.Map{
+		"message": "文件已删除",
+	})
+}
+
+// CreateUpload 创建分片上传
+// @Summary 创建分片上传
+// @Description 创建分片上传会话，之后按顺序以PATCH上传分片，全部上传后调用完成接口。会话在最后一次上传分片后的有效期内保留。已接收的内容保存在创建会话的节点上，多节点部署时需要会话保持，请求到达其他节点时返回503
+// @Tags 文件
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param params body service.CreateUploadParams true "文件信息"
+// @Success 201 {object} fiber.Map
+// @Router /api/v1/files/uploads [post]
+func (c *FileController) CreateUpload(ctx *fiber.Ctx) error {
+	var params service.CreateUploadParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	session, err := c.uploadService.Create(ctx.UserContext(), middleware.CurrentUser(ctx), params)
+	if err != nil {
+		return fileError(err)
+	}
+
+	ctx.Location("/api/v1/files/uploads/" + session.ID)
+	return ctx.Status(fiber.StatusCreated).JSON(uploadResponse(ctx, session))
+}
+
+// GetUploads 获取未完成的分片上传
+// @Summary 获取未完成的分片上传
+// @Description 获取当前用户未完成且未过期的分片上传会话
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/files/uploads [get]
+func (c *FileController) GetUploads(ctx *fiber.Ctx) error {
+	sessions, err := c.uploadService.List(ctx.UserContext(), middleware.CurrentUser(ctx).ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"uploads": sessions,
+	})
+}
+
+// GetUpload 查询分片上传进度
+// @Summary 查询分片上传进度
+// @Description 返回已接收的长度，同时以Upload-Offset和Upload-Length响应头给出，连接中断后从Upload-Offset处继续上传
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "上传会话ID"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/files/uploads/{id} [get]
+func (c *FileController) GetUpload(ctx *fiber.Ctx) error {
+	session, err := c.uploadService.Get(ctx.UserContext(), middleware.CurrentUser(ctx).ID, ctx.Params("id"))
+	if err != nil {
+		return fileError(err)
+	}
+	return ctx.JSON(uploadResponse(ctx, session))
+}
+
+// UploadChunk 上传分片
+// @Summary 上传分片
+// @Description 请求体为分片内容，Upload-Offset必须等于已接收的长度，不一致时返回409并在Upload-Offset响应头中给出正确的位置。可以通过Upload-Checksum提供分片的SHA-256哈希（格式为"sha256 <Base64>"），校验失败的分片会被丢弃
+// @Tags 文件
+// @Accept octet-stream
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "上传会话ID"
+// @Param Upload-Offset header int true "分片在文件中的偏移量"
+// @Param Upload-Checksum header string false "分片的SHA-256哈希"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/files/uploads/{id} [patch]
+func (c *FileController) UploadChunk(ctx *fiber.Ctx) error {
+	offset, err := strconv.ParseInt(ctx.Get(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的Upload-Offset")
+	}
+	digest, err := parseUploadChecksum(ctx.Get(uploadChecksumHeader))
+	if err != nil {
+		return fileError(err)
+	}
+
+	session, err := c.uploadService.WriteChunk(ctx.UserContext(), middleware.CurrentUser(ctx).ID, ctx.Params("id"), offset, requestBody(ctx), digest)
+	if err != nil {
+		var offsetErr *service.UploadOffsetError
+		if errors.As(err, &offsetErr) {
+			ctx.Set(uploadOffsetHeader, strconv.FormatInt(offsetErr.Offset, 10))
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+		return fileError(err)
+	}
+	return ctx.JSON(uploadResponse(ctx, session))
+}
+
+// CompleteUpload 完成分片上传
+// @Summary 完成分片上传
+// @Description 全部内容上传后调用，根据内容识别文件类型，创建会话时提供了checksum则校验整个文件的哈希，成功后返回文件记录
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "上传会话ID"
+// @Success 201 {object} model.File
+// @Router /api/v1/files/uploads/{id}/complete [post]
+func (c *FileController) CompleteUpload(ctx *fiber.Ctx) error {
+	file, err := c.uploadService.Complete(ctx.UserContext(), middleware.CurrentUser(ctx).ID, ctx.Params("id"))
+	if err != nil {
+		return fileError(err)
+	}
+	return ctx.Status(fiber.StatusCreated).JSON(resource.File.Serialize(file))
+}
+
+// AbortUpload 取消分片上传
+// @Summary 取消分片上传
+// @Description 取消分片上传并删除已接收的内容
+// @Tags 文件
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "上传会话ID"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/files/uploads/{id} [delete]
+func (c *FileController) AbortUpload(ctx *fiber.Ctx) error {
+	if err := c.uploadService.Abort(ctx.UserContext(), middleware.CurrentUser(ctx).ID, ctx.Params("id")); err != nil {
+		return fileError(err)
+	}
+	return ctx.JSON(fiber.Map{
+		"message": "上传已取消",
+	})
+}
+
+// parseRange 解析只包含一个范围的Range请求头，返回起始位置和长度
+// 范围无法满足时ok为false；格式无法识别或包含多个范围时返回长度0，由调用方忽略Range
+func parseRange(header string, size int64) (start, length int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, true
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, true
+	}
+
+	if first == "" {
+		// bytes=-n 表示最后n个字节
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, true
+		}
+		if n == 0 {
+			return 0, 0, false
+		}
+		n = min(n, size)
+		return size - n, n, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, true
+	}
+	if start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, true
+		}
+		end = min(end, size-1)
+	}
+	return start, end - start + 1, true
+}
+
+// scriptable 判断文件类型在浏览器中内联显示时是否可能执行脚本
+func scriptable(mimeType string) bool {
+	return mimeType == "text/html" || mimeType == "application/xhtml+xml" ||
+		mimeType == "text/xml" || mimeType == "application/xml" || strings.HasSuffix(mimeType, "+xml")
+}
+
+// etagMatches 判断If-None-Match是否包含指定的ETag，使用弱比较
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifRangeMatches 判断If-Range条件是否成立，不成立时应返回完整内容
+func ifRangeMatches(header, etag string, lastModified time.Time) bool {
+	if header == "" {
+		return true
+	}
+	if strings.HasPrefix(header, `"`) {
+		return header == etag
+	}
+	t, err := http.ParseTime(header)
+	return err == nil && t.Equal(lastModified)
+}
+
+// 分片上传使用的请求头和响应头
+const (
+	uploadOffsetHeader   = "Upload-Offset"
+	uploadLengthHeader   = "Upload-Length"
+	uploadChecksumHeader = "Upload-Checksum"
+)
+
+// uploadResponse 设置上传进度响应头并返回会话信息
+func uploadResponse(ctx *fiber.Ctx, session *model.UploadSession) fiber.Map {
+	ctx.Set(uploadOffsetHeader, strconv.FormatInt(session.Offset, 10))
+	ctx.Set(uploadLengthHeader, strconv.FormatInt(session.Size, 10))
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return fiber.Map{
+		"upload":   session,
+		"progress": session.Progress(),
+	}
+}
+
+// parseUploadChecksum 解析"sha256 <Base64>"格式的分片哈希，未提供时返回nil
+func parseUploadChecksum(value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+	algorithm, encoded, _ := strings.Cut(strings.TrimSpace(value), " ")
+	if !strings.EqualFold(algorithm, "sha256") {
+		return nil, service.ErrUnsupportedDigest
+	}
+	digest, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(digest) != sha256.Size {
+		return nil, service.ErrInvalidUpload
+	}
+	return digest, nil
+}
+
+// fileError 将文件服务的错误转换为HTTP错误
+func fileError(err error) error {
+	switch err {
+	case service.ErrFileNotFound, service.ErrUploadNotFound:
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case service.ErrDownloadLinkInvalid:
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	case service.ErrDownloadLinkUsed:
+		return fiber.NewError(fiber.StatusGone, err.Error())
+	case service.ErrUploadBusy, service.ErrUploadIncomplete, service.ErrTooManyUploads:
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case service.ErrUploadUnavailable:
+		return fiber.NewError(fiber.StatusServiceUnavailable, err.Error())
+	case service.ErrChecksumMismatch:
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	case service.ErrFileTooLarge:
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, err.Error())
+	case service.ErrFileTypeNotAllowed:
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, err.Error())
+	case service.ErrImageTooLarge:
+		return fiber.NewError(fiber.StatusUnprocessableEntity, err.Error())
+	case service.ErrInvalidUpload, service.ErrNoFiles, service.ErrTooManyFiles, service.ErrEmptyFile, service.ErrInvalidImage, service.ErrUnsupportedDigest, service.ErrDownloadLinkTTL:
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return err
+}
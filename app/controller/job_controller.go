@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/NextEraAbyss/fiber-template/app/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// JobController 定时任务管理控制器
+type JobController struct {
+	jobService *service.JobService
+}
+
+// NewJobController 创建新的定时任务管理控制器实例
+func NewJobController() *JobController {
+	return &JobController{
+		jobService: service.NewJobService(),
+	}
+}
+
+// GetJobs 获取定时任务列表
+// @Summary 获取定时任务列表
+// @Description 获取所有已注册的定时任务及其下一次执行时间和暂停状态（仅管理员）
+// @Tags 定时任务管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/admin/jobs [get]
+func (c *JobController) GetJobs(ctx *fiber.Ctx) error {
+	jobs, err := c.jobService.GetJobs(ctx.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"jobs": jobs,
+	})
+}
+
+// GetJobRuns 获取定时任务执行记录
+// @Summary 获取定时任务执行记录
+// @Description 获取定时任务最近的执行记录（仅管理员）
+// @Tags 定时任务管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "任务名称"
+// @Param limit query int false "返回数量，默认20，最大100"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/admin/jobs/{name}/runs [get]
+func (c *JobController) GetJobRuns(ctx *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(ctx.Query("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	runs, err := c.jobService.GetJobRuns(ctx.UserContext(), ctx.Params("name"), limit)
+	if err != nil {
+		return jobError(err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"runs": runs,
+	})
+}
+
+// TriggerJob 立即执行定时任务
+// @Summary 立即执行定时任务
+// @Description 在当前节点立即执行一次定时任务（仅管理员）
+// @Tags 定时任务管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "任务名称"
+// @Success 202 {object} fiber.Map
+// @Router /api/v1/admin/jobs/{name}/trigger [post]
+func (c *JobController) TriggerJob(ctx *fiber.Ctx) error {
+	if err := c.jobService.TriggerJob(ctx.Params("name")); err != nil {
+		return jobError(err)
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "任务已触发",
+	})
+}
+
+// PauseJob 暂停定时任务
+// @Summary 暂停定时任务
+// @Description 暂停定时任务的计划执行，对所有节点生效（仅管理员）
+// @Tags 定时任务管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "任务名称"
+// @Success 200 {object} service.JobInfo
+// @Router /api/v1/admin/jobs/{name}/pause [post]
+func (c *JobController) PauseJob(ctx *fiber.Ctx) error {
+	name := ctx.Params("name")
+	if err := c.jobService.PauseJob(ctx.UserContext(), name); err != nil {
+		return jobError(err)
+	}
+	return c.respondJob(ctx, name)
+}
+
+// ResumeJob 恢复定时任务
+// @Summary 恢复定时任务
+// @Description 恢复已暂停的定时任务（仅管理员）
+// @Tags 定时任务管理
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "任务名称"
+// @Success 200 {object} service.JobInfo
+// @Router /api/v1/admin/jobs/{name}/resume [post]
+func (c *JobController) ResumeJob(ctx *fiber.Ctx) error {
+	name := ctx.Params("name")
+	if err := c.jobService.ResumeJob(ctx.UserContext(), name); err != nil {
+		return jobError(err)
+	}
+	return c.respondJob(ctx, name)
+}
+
+// respondJob 返回定时任务的最新状态
+func (c *JobController) respondJob(ctx *fiber.Ctx, name string) error {
+	job, err := c.jobService.GetJob(ctx.UserContext(), name)
+	if err != nil {
+		return jobError(err)
+	}
+	return ctx.JSON(job)
+}
+
+// jobError 将定时任务错误转换为HTTP错误
+func jobError(err error) error {
+	if err == service.ErrJobNotFound {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if service.IsJobConflict(err) {
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"github.com/NextEraAbyss/fiber-template/app/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// previewData 预览邮件模板时使用的示例数据，包含所有内置模板用到的字段
+var previewData = map[string]interface{}{
+	"Username":       "preview",
+	"Email":          "preview@example.com",
+	"NewEmail":       "new@example.com",
+	"URL":            "http://localhost:3000/preview",
+	"ExpiresMinutes": 60,
+}
+
+// MailController 邮件模板控制器，仅在调试模式下注册
+type MailController struct {
+	mailService *service.MailService
+}
+
+// NewMailController 创建新的邮件模板控制器实例
+func NewMailController() *MailController {
+	return &MailController{
+		mailService: service.NewMailService(),
+	}
+}
+
+// GetTemplates 获取邮件模板列表
+// @Summary 获取邮件模板列表
+// @Description 获取所有内置邮件模板名称和支持的语言（仅调试模式）
+// @Tags 开发
+// @Produce json
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/dev/mail [get]
+func (c *MailController) GetTemplates(ctx *fiber.Ctx) error {
+	names, locales := c.mailService.Templates()
+	return ctx.JSON(fiber.Map{
+		"templates": names,
+		"locales":   locales,
+	})
+}
+
+// PreviewTemplate 预览邮件模板
+// @Summary 预览邮件模板
+// @Description 使用示例数据渲染邮件模板（仅调试模式）
+// @Tags 开发
+// @Produce html
+// @Param name path string true "模板名称"
+// @Param locale query string false "语言，默认为应用语言"
+// @Param format query string false "html（默认）或 text"
+// @Success 200 {string} string
+// @Router /api/v1/dev/mail/{name} [get]
+func (c *MailController) PreviewTemplate(ctx *fiber.Ctx) error {
+	msg, err := c.mailService.Render(ctx.Params("name"), ctx.Query("locale"), previewData)
+	if err != nil {
+		if err == service.ErrMailTemplateNotFound {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+		return fiber.NewError(fiber.StatusInternalServerError, "渲染邮件模板失败: "+err.Error())
+	}
+
+	// HTML预览的主题在<title>中，纯文本预览将主题放在第一行
+	if ctx.Query("format") == "text" || msg.HTML == "" {
+		ctx.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return ctx.SendString("Subject: " + msg.Subject + "\n\n" + msg.Text)
+	}
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return ctx.SendString(msg.HTML)
+}
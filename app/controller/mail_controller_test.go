@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/NextEraAbyss/fiber-template/app/mail"
+)
+
+// 新增模板用到的字段必须加入previewData，否则预览接口渲染失败
+func TestPreviewDataRendersAllTemplates(t *testing.T) {
+	renderer, err := mail.NewRenderer(mail.DefaultTemplates(), mail.App{Name: "Test", URL: "http://localhost"}, "en")
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+
+	for _, locale := range renderer.Locales() {
+		for _, name := range renderer.Names() {
+			if _, err := renderer.Render(name, locale, previewData); err != nil {
+				t.Errorf("%s/%s: %v", locale, name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/resource"
+	"github.com/NextEraAbyss/fiber-template/app/service"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// oidcStateCookie 保存登录请求state的Cookie，回调时与参数中的state比对
+const oidcStateCookie = "oidc_state"
+
+// OIDCController OpenID Connect登录控制器
+type OIDCController struct {
+	config      *config.Config
+	oidcService *service.OIDCService
+}
+
+// NewOIDCController 创建新的OpenID Connect登录控制器实例
+func NewOIDCController() *OIDCController {
+	return &OIDCController{
+		config:      config.Load(),
+		oidcService: service.NewOIDCService(),
+	}
+}
+
+// GetProviders 获取可用的第三方登录方式
+// @Summary 获取第三方登录方式
+// @Description 获取已配置的OpenID Connect身份提供方
+// @Tags 认证
+// @Produce json
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/auth/oidc [get]
+func (c *OIDCController) GetProviders(ctx *fiber.Ctx) error {
+	return ctx.JSON(fiber.Map{
+		"providers": c.oidcService.Providers(),
+	})
+}
+
+// Redirect 跳转到身份提供方登录
+// @Summary 第三方登录
+// @Description 使用授权码模式和PKCE跳转到身份提供方登录页面
+// @Tags 认证
+// @Param provider path string true "身份提供方名称"
+// @Success 302
+// @Router /api/v1/auth/oidc/{provider} [get]
+func (c *OIDCController) Redirect(ctx *fiber.Ctx) error {
+	authURL, state, err := c.oidcService.Begin(ctx.UserContext(), ctx.Params("provider"))
+	if err != nil {
+		return oidcError(err)
+	}
+
+	ctx.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/v1/auth/oidc",
+		Expires:  time.Now().Add(c.config.OIDC.StateTTL),
+		Secure:   c.config.Security.CookieSecure,
+		HTTPOnly: true,
+		// 身份提供方跳转回来属于跨站导航，Strict会导致Cookie不被发送
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return ctx.Redirect(authURL, fiber.StatusFound)
+}
+
+// Callback 身份提供方登录回调
+// @Summary 第三方登录回调
+// @Description 校验state并换取ID令牌后登录，返回JWT令牌；配置了OIDC_SUCCESS_URL时跳转到该地址并以URL片段传递令牌
+// @Tags 认证
+// @Produce json
+// @Param provider path string true "身份提供方名称"
+// @Param code query string false "授权码"
+// @Param state query string true "登录请求state"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/auth/oidc/{provider}/callback [get]
+func (c *OIDCController) Callback(ctx *fiber.Ctx) error {
+	state := ctx.Query("state")
+	cookie := ctx.Cookies(oidcStateCookie)
+	ctx.ClearCookie(oidcStateCookie)
+
+	var result *service.LoginResult
+	err := service.ErrOIDCInvalidState
+	if cookie != "" && cookie == state {
+		result, err = c.oidcService.Callback(ctx.UserContext(), ctx.Params("provider"), service.OIDCCallbackParams{
+			Code:             ctx.Query("code"),
+			State:            state,
+			Error:            ctx.Query("error"),
+			ErrorDescription: ctx.Query("error_description"),
+		}, clientInfo(ctx))
+	}
+
+	if c.config.OIDC.SuccessURL != "" {
+		fragment := url.Values{}
+		switch {
+		case err != nil:
+			fragment.Set("error", err.Error())
+		case result.ChallengeToken != "":
+			fragment.Set("two_factor_required", "true")
+			fragment.Set("challenge_token", result.ChallengeToken)
+		default:
+			fragment.Set("token", result.Token)
+			fragment.Set("token_type", "Bearer")
+		}
+		return ctx.Redirect(c.config.OIDC.SuccessURL+"#"+fragment.Encode(), fiber.StatusFound)
+	}
+
+	if err != nil {
+		return oidcError(err)
+	}
+	if result.ChallengeToken != "" {
+		return ctx.JSON(fiber.Map{
+			"two_factor_required": true,
+			"challenge_token":     result.ChallengeToken,
+		})
+	}
+	return ctx.JSON(fiber.Map{
+		"token":      result.Token,
+		"token_type": "Bearer",
+		"user":       resource.User.Serialize(result.User),
+	})
+}
+
+// oidcError 将第三方登录的错误转换为HTTP错误
+func oidcError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrOIDCProviderNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrOIDCInvalidState):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrOIDCLoginFailed):
+		return fiber.NewError(fiber.StatusBadGateway, err.Error())
+	case errors.Is(err, service.ErrOIDCAccountUnverified):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrOIDCEmailNotVerified), errors.Is(err, service.ErrOIDCNotRegistered),
+		errors.Is(err, service.ErrUserDisabled):
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	}
+	return err
+}
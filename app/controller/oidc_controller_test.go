@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/cache"
+	"github.com/NextEraAbyss/fiber-template/app/oidc"
+	"github.com/NextEraAbyss/fiber-template/app/oidc/oidctest"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	idp := oidctest.NewServer(t)
+	config.Cache = cache.New(cache.NewMemoryDriver(100), "test:", time.Minute, false)
+	config.OIDCProviders = map[string]*oidc.Provider{
+		"test": oidc.NewProvider(oidc.Config{Name: "test", Issuer: idp.URL, ClientID: "client-1"}, idp.Client()),
+	}
+
+	c := NewOIDCController()
+	app := fiber.New(fiber.Config{ErrorHandler: config.ErrorHandler})
+	app.Get("/api/v1/auth/oidc/:provider", c.Redirect)
+	app.Get("/api/v1/auth/oidc/:provider/callback", c.Callback)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/test", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("redirect status = %d", resp.StatusCode)
+	}
+	var state string
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == oidcStateCookie {
+			state = cookie.Value
+		}
+	}
+	if state == "" {
+		t.Fatal("未设置state Cookie")
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		cookie string
+	}{
+		{"missing cookie", state, ""},
+		{"cookie mismatch", state, "other"},
+		{"query mismatch", "other", state},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/test/callback?code=code&state="+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: tt.cookie})
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"strconv"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/service"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseBody 解析并验证请求体
+func parseBody(ctx *fiber.Ctx, out interface{}) error {
+	if err := limitBody(ctx); err != nil {
+		return err
+	}
+	if err := ctx.BodyParser(out); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的请求参数")
+	}
+
+	if errs := config.ValidateStruct(out); len(errs) > 0 {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("参数 %s 验证失败: %s", errs[0].Field, errs[0].Tag))
+	}
+	return nil
+}
+
+// limitBody 将请求体读入内存，超过BodyLimit时返回413
+// 开启StreamRequestBody后超过BodyLimit的请求体不会被拒绝，直接调用ctx.Body()会把整个请求体读入内存，
+// 除文件上传外，读取请求体前都需要先调用此函数
+func limitBody(ctx *fiber.Ctx) error {
+	limit := ctx.App().Config().BodyLimit
+	if ctx.Request().Header.ContentLength() > limit {
+		return bodyTooLarge(ctx)
+	}
+	stream := ctx.Context().RequestBodyStream()
+	if stream == nil {
+		return nil
+	}
+
+	// 分块传输的请求体没有Content-Length，需要边读边检查
+	body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "读取请求体失败")
+	}
+	if len(body) > limit {
+		return bodyTooLarge(ctx)
+	}
+	ctx.Request().SetBodyRaw(body)
+	return nil
+}
+
+// bodyTooLarge 返回413错误并关闭连接，未读取的请求体不能留在连接中被当作下一个请求解析
+func bodyTooLarge(ctx *fiber.Ctx) error {
+	ctx.Context().SetConnectionClose()
+	return fiber.NewError(fiber.StatusRequestEntityTooLarge, "请求体过大")
+}
+
+// multipartBody 返回multipart请求体和分隔符
+func multipartBody(ctx *fiber.Ctx) (io.Reader, string, error) {
+	mediaType, params, err := mime.ParseMediaType(ctx.Get(fiber.HeaderContentType))
+	if err != nil || mediaType != fiber.MIMEMultipartForm || params["boundary"] == "" {
+		return nil, "", fiber.NewError(fiber.StatusBadRequest, "请使用multipart/form-data上传文件")
+	}
+	return requestBody(ctx), params["boundary"], nil
+}
+
+// requestBody 返回请求体，超过BodyLimit时以流的形式读取，不会整体读入内存
+func requestBody(ctx *fiber.Ctx) io.Reader {
+	if body := ctx.Context().RequestBodyStream(); body != nil {
+		return body
+	}
+	return bytes.NewReader(ctx.Body())
+}
+
+// clientInfo 返回请求的客户端信息，用于审计日志
+func clientInfo(ctx *fiber.Ctx) service.ClientInfo {
+	return service.ClientInfo{
+		IP:        ctx.IP(),
+		UserAgent: ctx.Get(fiber.HeaderUserAgent),
+	}
+}
+
+// tooManyRequests 返回429错误并设置Retry-After响应头
+func tooManyRequests(ctx *fiber.Ctx, err *service.RateLimitError) error {
+	setRetryAfter(ctx, err.RetryAfter)
+	return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+}
+
+// accountLocked 返回423错误并设置Retry-After响应头
+func accountLocked(ctx *fiber.Ctx, err *service.AccountLockedError) error {
+	setRetryAfter(ctx, err.RetryAfter)
+	return fiber.NewError(fiber.StatusLocked, err.Error())
+}
+
+// setRetryAfter 设置Retry-After响应头，单位为秒并向上取整
+func setRetryAfter(ctx *fiber.Ctx, d time.Duration) {
+	ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// StatisticsController 统计数据控制器
+type StatisticsController struct {
+	statisticsService *service.StatisticsService
+}
+
+// NewStatisticsController 创建新的统计数据控制器实例
+func NewStatisticsController() *StatisticsController {
+	return &StatisticsController{
+		statisticsService: service.NewStatisticsService(),
+	}
+}
+
+// GetUserStatistics 获取每日用户统计
+// @Summary 获取每日用户统计
+// @Description 获取日期范围内的每日新增用户、激活状态和角色分布（仅管理员）
+// @Tags 统计
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string false "开始日期，格式 2006-01-02，默认30天前"
+// @Param end_date query string false "结束日期，格式 2006-01-02，默认今天"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/stats/users [get]
+func (c *StatisticsController) GetUserStatistics(ctx *fiber.Ctx) error {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	end, err := parseDate(ctx.Query("end_date"), today)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的结束日期")
+	}
+	start, err := parseDate(ctx.Query("start_date"), end.AddDate(0, 0, -29))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的开始日期")
+	}
+
+	stats, err := c.statisticsService.GetUserStatistics(ctx.UserContext(), start, end)
+	if err != nil {
+		if err == service.ErrInvalidDateRange {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"start_date": start.Format(time.DateOnly),
+		"end_date":   end.Format(time.DateOnly),
+		"statistics": stats,
+	})
+}
+
+// parseDate 解析 2006-01-02 格式的日期，为空时返回默认值
+func parseDate(value string, defaultValue time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.ParseInLocation(time.DateOnly, value, time.Local)
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/NextEraAbyss/fiber-template/app/middleware"
+	"github.com/NextEraAbyss/fiber-template/app/resource"
+	"github.com/NextEraAbyss/fiber-template/app/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TwoFactorController 两步验证控制器
+type TwoFactorController struct {
+	authService      *service.AuthService
+	twoFactorService *service.TwoFactorService
+}
+
+// NewTwoFactorController 创建新的两步验证控制器实例
+func NewTwoFactorController() *TwoFactorController {
+	return &TwoFactorController{
+		authService:      service.NewAuthService(),
+		twoFactorService: service.NewTwoFactorService(),
+	}
+}
+
+// Verify 两步验证登录
+// @Summary 两步验证登录
+// @Description 使用登录返回的challenge_token和验证码（或恢复码）换取JWT令牌
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Param body body service.TwoFactorLoginParams true "两步验证登录参数"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/auth/2fa/verify [post]
+func (c *TwoFactorController) Verify(ctx *fiber.Ctx) error {
+	var params service.TwoFactorLoginParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	result, err := c.authService.VerifyTwoFactor(ctx.UserContext(), params, clientInfo(ctx))
+	if err != nil {
+		var limited *service.RateLimitError
+		var locked *service.AccountLockedError
+		switch {
+		case errors.As(err, &limited):
+			return tooManyRequests(ctx, limited)
+		case errors.As(err, &locked):
+			return accountLocked(ctx, locked)
+		case err == service.ErrInvalidChallenge, err == service.ErrInvalidTwoFactor:
+			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+		case err == service.ErrUserDisabled:
+			return fiber.NewError(fiber.StatusForbidden, err.Error())
+		}
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"token":      result.Token,
+		"token_type": "Bearer",
+		"user":       resource.User.Serialize(result.User),
+	})
+}
+
+// Setup 获取两步验证密钥
+// @Summary 获取两步验证密钥
+// @Description 生成TOTP密钥和otpauth链接，需调用确认接口后才会启用
+// @Tags 两步验证
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.TwoFactorSetup
+// @Router /api/v1/auth/2fa/setup [post]
+func (c *TwoFactorController) Setup(ctx *fiber.Ctx) error {
+	setup, err := c.twoFactorService.Setup(ctx.UserContext(), middleware.CurrentUser(ctx))
+	if err != nil {
+		return twoFactorError(ctx, err)
+	}
+	return ctx.JSON(setup)
+}
+
+// Confirm 确认启用两步验证
+// @Summary 确认启用两步验证
+// @Description 使用验证器应用生成的验证码确认启用，返回只显示一次的恢复码
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body service.TwoFactorCodeParams true "验证码"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/auth/2fa/confirm [post]
+func (c *TwoFactorController) Confirm(ctx *fiber.Ctx) error {
+	var params service.TwoFactorCodeParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	codes, err := c.twoFactorService.Confirm(ctx.UserContext(), middleware.CurrentUser(ctx), params.Code, clientInfo(ctx))
+	if err != nil {
+		return twoFactorError(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Description 使用验证码确认后作废所有恢复码并重新生成
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body service.TwoFactorCodeParams true "验证码"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/auth/2fa/recovery-codes [post]
+func (c *TwoFactorController) RegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	var params service.TwoFactorCodeParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	codes, err := c.twoFactorService.RegenerateRecoveryCodes(ctx.UserContext(), middleware.CurrentUser(ctx), params.Code, clientInfo(ctx))
+	if err != nil {
+		return twoFactorError(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+// Disable 关闭两步验证
+// @Summary 关闭两步验证
+// @Description 校验密码和验证码（或恢复码）后关闭两步验证
+// @Tags 两步验证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body service.DisableTwoFactorParams true "关闭两步验证参数"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/auth/2fa/disable [post]
+func (c *TwoFactorController) Disable(ctx *fiber.Ctx) error {
+	var params service.DisableTwoFactorParams
+	if err := parseBody(ctx, &params); err != nil {
+		return err
+	}
+
+	err := c.twoFactorService.Disable(ctx.UserContext(), middleware.CurrentUser(ctx), params, clientInfo(ctx))
+	if err != nil {
+		return twoFactorError(ctx, err)
+	}
+	return ctx.JSON(fiber.Map{
+		"message": "两步验证已关闭",
+	})
+}
+
+// twoFactorError 将两步验证服务的错误转换为HTTP错误
+func twoFactorError(ctx *fiber.Ctx, err error) error {
+	var locked *service.AccountLockedError
+	if errors.As(err, &locked) {
+		return accountLocked(ctx, locked)
+	}
+	switch err {
+	case service.ErrTwoFactorEnabled:
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case service.ErrTwoFactorNotEnabled, service.ErrTwoFactorNotSetup, service.ErrInvalidTwoFactor:
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case service.ErrInvalidCredentials:
+		return fiber.NewError(fiber.StatusUnauthorized, "密码错误")
+	}
+	return err
+}
@@ -2,20 +2,28 @@ package controller
 
 import (
 	"strconv"
+	"strings"
 
+	"github.com/NextEraAbyss/fiber-template/app/filter"
+	"github.com/NextEraAbyss/fiber-template/app/middleware"
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/pagination"
+	"github.com/NextEraAbyss/fiber-template/app/resource"
 	"github.com/NextEraAbyss/fiber-template/app/service"
 	"github.com/gofiber/fiber/v2"
 )
 
 // UserController 用户控制器
 type UserController struct {
-	userService *service.UserService
+	userService   *service.UserService
+	avatarService *service.AvatarService
 }
 
 // NewUserController 创建新的用户控制器实例
 func NewUserController() *UserController {
 	return &UserController{
-		userService: service.NewUserService(),
+		userService:   service.DefaultUserService(),
+		avatarService: service.NewAvatarService(),
 	}
 }
 
@@ -25,37 +33,53 @@ func NewUserController() *UserController {
 // @Tags 用户管理
 // @Accept json
 // @Produce json
-// @Param page query int false "页码"
-// @Param page_size query int false "每页数量"
-// @Param username query string false "用户名"
-// @Param email query string false "邮箱"
-// @Param role query string false "角色"
-// @Param is_active query string false "是否激活"
+// @Param page_size query int false "每页数量，1到100，默认20"
+// @Param sort query string false "排序字段：id、username、created_at，前缀-表示降序"
+// @Param cursor query string false "分页游标，取自上一页的next_cursor"
+// @Param page query int false "页码，指定时使用偏移分页，不能与cursor同时使用"
+// @Param filter[username][like] query string false "用户名包含，可筛选字段：username、email、role、is_active、created_at，管理员还可筛选email_verified_at"
+// @Param filter[role] query string false "角色"
+// @Param fields query string false "逗号分隔的输出字段，默认输出全部字段"
+// @Param include query string false "加载的关联数据：identities，仅本人和管理员可见"
 // @Success 200 {object} fiber.Map
+// @Header 200 {string} Link "首页和下一页的地址"
 // @Router /api/v1/users [get]
 func (c *UserController) GetUsers(ctx *fiber.Ctx) error {
-	// 获取查询参数
-	page, _ := strconv.Atoi(ctx.Query("page", "1"))
-	pageSize, _ := strconv.Atoi(ctx.Query("page_size", "10"))
+	page, err := pagination.Parse(ctx, service.UserListOptions)
+	if err != nil {
+		return err
+	}
+
+	viewer := middleware.CurrentUser(ctx)
+	fields := service.PublicUserFilters
+	if viewer != nil && viewer.Role == model.RoleAdmin {
+		fields = service.UserFilters
+	}
+	f, err := filter.Parse(ctx, fields)
+	if err != nil {
+		return err
+	}
 
-	params := service.UserQueryParams{
-		Page:     page,
-		PageSize: pageSize,
-		Username: ctx.Query("username"),
-		Email:    ctx.Query("email"),
-		Role:     ctx.Query("role"),
-		IsActive: ctx.Query("is_active"),
+	sel, err := resource.User.Parse(ctx)
+	if err != nil {
+		return err
 	}
+	sel.Owner(resource.OwnerOf(viewer))
 
 	// 获取用户列表
-	users, pagination, err := c.userService.GetUsers(params)
+	users, result, err := c.userService.GetUsers(ctx.UserContext(), page, f.Scope, sel.Scope)
+	if err != nil {
+		return err
+	}
+	out, err := sel.Many(ctx, users)
 	if err != nil {
 		return err
 	}
 
+	pagination.SetLinks(ctx, page, result)
 	return ctx.JSON(fiber.Map{
-		"users":      users,
-		"pagination": pagination,
+		"users":      out,
+		"pagination": result,
 	})
 }
 
@@ -66,15 +90,71 @@ func (c *UserController) GetUsers(ctx *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param id path int true "用户ID"
-// @Success 200 {object} model.User
+// @Param fields query string false "逗号分隔的输出字段，默认输出全部字段"
+// @Param include query string false "加载的关联数据：identities，仅本人和管理员可见"
+// @Success 200 {object} fiber.Map
 // @Router /api/v1/users/{id} [get]
 func (c *UserController) GetUser(ctx *fiber.Ctx) error {
 	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
 	if err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "无效的用户ID")
 	}
+	sel, err := resource.User.Parse(ctx)
+	if err != nil {
+		return err
+	}
+	sel.Owner(resource.OwnerOf(middleware.CurrentUser(ctx)))
+
+	user, err := c.userService.GetUserByID(ctx.UserContext(), uint(id))
+	if err != nil {
+		if err == service.ErrUserNotFound {
+			return fiber.NewError(fiber.StatusNotFound, "用户不存在")
+		}
+		return err
+	}
+
+	out, err := sel.One(ctx, user)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(out)
+}
+
+// GetLockedUsers 获取登录锁定的用户
+// @Summary 获取登录锁定的用户
+// @Description 获取处于锁定期或有登录失败记录的用户（仅管理员）
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/admin/users/locked [get]
+func (c *UserController) GetLockedUsers(ctx *fiber.Ctx) error {
+	users, err := c.userService.GetLockedUsers(ctx.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(fiber.Map{
+		"users": users,
+	})
+}
+
+// UnlockUser 解除用户登录锁定
+// @Summary 解除用户登录锁定
+// @Description 清除用户的登录失败次数和锁定状态（仅管理员）
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/admin/users/{id}/unlock [post]
+func (c *UserController) UnlockUser(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的用户ID")
+	}
 
-	user, err := c.userService.GetUserByID(uint(id))
+	err = c.userService.UnlockUser(ctx.UserContext(), uint(id), middleware.CurrentUser(ctx), clientInfo(ctx))
 	if err != nil {
 		if err == service.ErrUserNotFound {
 			return fiber.NewError(fiber.StatusNotFound, "用户不存在")
@@ -82,5 +162,106 @@ func (c *UserController) GetUser(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	return ctx.JSON(user)
+	return ctx.JSON(fiber.Map{
+		"message": "已解除锁定",
+	})
+}
+
+// UploadAvatar 上传用户头像
+// @Summary 上传用户头像
+// @Description 以multipart/form-data上传头像图片，支持JPEG、PNG和GIF，尺寸受上传配置限制；图片会重新编码并去除元数据，同时生成缩略图。用户只能修改自己的头像，管理员可以修改任意用户的头像
+// @Tags 用户管理
+// @Accept mpfd
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param avatar formData file true "头像图片"
+// @Success 200 {object} service.AvatarURLs
+// @Router /api/v1/users/{id}/avatar [post]
+func (c *UserController) UploadAvatar(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的用户ID")
+	}
+	body, boundary, err := multipartBody(ctx)
+	if err != nil {
+		return err
+	}
+
+	urls, err := c.avatarService.Upload(ctx.UserContext(), middleware.CurrentUser(ctx), uint(id), body, boundary)
+	if err != nil {
+		return avatarError(err)
+	}
+	return ctx.JSON(urls)
+}
+
+// DeleteAvatar 删除用户头像
+// @Summary 删除用户头像
+// @Description 删除用户头像及其缩略图。用户只能删除自己的头像，管理员可以删除任意用户的头像
+// @Tags 用户管理
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} fiber.Map
+// @Router /api/v1/users/{id}/avatar [delete]
+func (c *UserController) DeleteAvatar(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "无效的用户ID")
+	}
+
+	if err := c.avatarService.Delete(ctx.UserContext(), middleware.CurrentUser(ctx), uint(id)); err != nil {
+		return avatarError(err)
+	}
+	return ctx.JSON(fiber.Map{
+		"message": "头像已删除",
+	})
+}
+
+// GetAvatar 获取头像图片
+// @Summary 获取头像图片
+// @Description 获取用户头像或缩略图，文件名由上传头像时返回的地址给出，内容不会改变，可以长期缓存
+// @Tags 用户管理
+// @Produce jpeg
+// @Param id path int true "用户ID"
+// @Param name path string true "文件名"
+// @Success 200 {file} file
+// @Router /api/v1/avatars/{id}/{name} [get]
+func (c *UserController) GetAvatar(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, service.ErrAvatarNotFound.Error())
+	}
+
+	// 头像文件名包含随机值，更换头像时会使用新的文件名
+	name := ctx.Params("name")
+	etag := `"` + strings.TrimSuffix(name, ".jpg") + `"`
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+	if ctx.Get(fiber.HeaderIfNoneMatch) == etag {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	content, err := c.avatarService.Open(ctx.UserContext(), uint(id), name)
+	if err != nil {
+		ctx.Set(fiber.HeaderCacheControl, "no-store")
+		return avatarError(err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "image/jpeg")
+	ctx.Set(fiber.HeaderETag, etag)
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return ctx.SendStream(content)
+}
+
+// avatarError 将头像服务的错误转换为HTTP错误
+func avatarError(err error) error {
+	switch err {
+	case service.ErrUserNotFound:
+		return fiber.NewError(fiber.StatusNotFound, "用户不存在")
+	case service.ErrAvatarNotFound:
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case service.ErrAvatarUpdateDenied:
+		return fiber.NewError(fiber.StatusForbidden, err.Error())
+	}
+	return fileError(err)
 }
@@ -0,0 +1,236 @@
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Type 筛选字段的值类型
+type Type int
+
+// 支持的值类型
+const (
+	String Type = iota
+	Int
+	Bool
+	Time // RFC3339格式的时间或2006-01-02格式的日期
+)
+
+// Operator 筛选运算符
+type Operator string
+
+// 支持的运算符
+const (
+	Eq      Operator = "eq"
+	Ne      Operator = "ne"
+	Gt      Operator = "gt"
+	Gte     Operator = "gte"
+	Lt      Operator = "lt"
+	Lte     Operator = "lte"
+	In      Operator = "in"      // 逗号分隔的多个值
+	Like    Operator = "like"    // 包含子串，%和_按普通字符匹配
+	Between Operator = "between" // 逗号分隔的两个值，包含两端
+	Null    Operator = "null"    // true表示为NULL，false表示不为NULL
+)
+
+// maxInValues in运算符最多允许的值数量
+const maxInValues = 100
+
+// sqlOperators 比较运算符对应的SQL
+var sqlOperators = map[Operator]string{
+	Eq:  "=",
+	Ne:  "<>",
+	Gt:  ">",
+	Gte: ">=",
+	Lt:  "<",
+	Lte: "<=",
+}
+
+// Field 允许筛选的字段
+type Field struct {
+	Column    string     // 数据库列名，为空时与筛选字段同名
+	Type      Type       // 值类型
+	Operators []Operator // 允许的运算符，为空时使用类型的默认运算符
+	Values    []string   // 允许的取值，为空表示不限制
+	Nullable  bool       // 是否允许使用null运算符
+}
+
+// Fields 模型允许筛选的字段，以查询参数中的字段名为键
+type Fields map[string]Field
+
+// operators 返回字段允许的运算符
+func (f Field) operators() []Operator {
+	var ops []Operator
+	switch {
+	case len(f.Operators) > 0:
+		ops = f.Operators
+	case f.Type == String && len(f.Values) > 0:
+		ops = []Operator{Eq, Ne, In}
+	case f.Type == String:
+		ops = []Operator{Eq, Ne, In, Like}
+	case f.Type == Bool:
+		ops = []Operator{Eq}
+	default:
+		ops = []Operator{Eq, Ne, Gt, Gte, Lt, Lte, In, Between}
+	}
+	if f.Nullable {
+		ops = append(slices.Clip(ops), Null)
+	}
+	return ops
+}
+
+// condition 一个筛选条件
+type condition struct {
+	column string
+	op     Operator
+	values []interface{}
+}
+
+// Filter 解析后的筛选条件，各条件之间为AND关系
+type Filter struct {
+	conditions []condition
+}
+
+// Parse 解析 filter[字段]=值 和 filter[字段][运算符]=值 形式的查询参数，省略运算符时为eq
+// 字段、运算符或值无效时返回400错误
+func Parse(ctx *fiber.Ctx, fields Fields) (*Filter, error) {
+	f := &Filter{}
+	var err error
+	ctx.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		if err != nil {
+			return
+		}
+		name, op, ok := parseKey(string(key))
+		if !ok {
+			return
+		}
+		var cond *condition
+		if cond, err = parseCondition(fields, name, op, string(value)); err == nil {
+			f.conditions = append(f.conditions, *cond)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Scope 返回添加筛选条件的GORM作用域，用于db.Scopes
+func (f *Filter) Scope(db *gorm.DB) *gorm.DB {
+	for _, c := range f.conditions {
+		column := clause.Column{Name: c.column}
+		switch c.op {
+		case In:
+			db = db.Where("? IN ?", column, c.values)
+		case Like:
+			db = db.Where("? LIKE ? ESCAPE '!'", column, c.values[0])
+		case Between:
+			db = db.Where("? BETWEEN ? AND ?", column, c.values[0], c.values[1])
+		case Null:
+			if c.values[0].(bool) {
+				db = db.Where("? IS NULL", column)
+			} else {
+				db = db.Where("? IS NOT NULL", column)
+			}
+		default:
+			db = db.Where("? "+sqlOperators[c.op]+" ?", column, c.values[0])
+		}
+	}
+	return db
+}
+
+// parseKey 解析查询参数名，返回字段名和运算符，不是筛选参数时ok为false
+func parseKey(key string) (name string, op Operator, ok bool) {
+	rest, ok := strings.CutPrefix(key, "filter[")
+	if !ok {
+		return "", "", false
+	}
+	name, rest, ok = strings.Cut(rest, "]")
+	if !ok {
+		return "", "", false
+	}
+	switch {
+	case rest == "":
+		return name, Eq, true
+	case strings.HasPrefix(rest, "[") && strings.HasSuffix(rest, "]"):
+		return name, Operator(rest[1 : len(rest)-1]), true
+	}
+	// 格式错误的参数按未知运算符处理
+	return name, Operator(rest), true
+}
+
+// parseCondition 校验字段和运算符并按字段类型转换值
+func parseCondition(fields Fields, name string, op Operator, raw string) (*condition, error) {
+	field, ok := fields[name]
+	if !ok {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "不支持的筛选字段: "+name)
+	}
+	if !slices.Contains(field.operators(), op) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("筛选字段 %s 不支持运算符 %s", name, op))
+	}
+	cond := &condition{column: field.Column, op: op}
+	if cond.column == "" {
+		cond.column = name
+	}
+	invalid := fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("筛选字段 %s 的值无效", name))
+
+	switch op {
+	case Null:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, invalid
+		}
+		cond.values = []interface{}{b}
+		return cond, nil
+	case Like:
+		cond.values = []interface{}{"%" + escapeLike(raw) + "%"}
+		return cond, nil
+	}
+
+	parts := []string{raw}
+	if op == In || op == Between {
+		parts = strings.Split(raw, ",")
+	}
+	if op == In && len(parts) > maxInValues || op == Between && len(parts) != 2 {
+		return nil, invalid
+	}
+	for _, part := range parts {
+		value, err := field.parse(strings.TrimSpace(part))
+		if err != nil {
+			return nil, invalid
+		}
+		cond.values = append(cond.values, value)
+	}
+	return cond, nil
+}
+
+// parse 按字段类型转换值
+func (f Field) parse(s string) (interface{}, error) {
+	switch f.Type {
+	case Int:
+		return strconv.ParseInt(s, 10, 64)
+	case Bool:
+		return strconv.ParseBool(s)
+	case Time:
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+		return time.ParseInLocation(time.DateOnly, s, time.Local)
+	}
+	if len(f.Values) > 0 && !slices.Contains(f.Values, s) {
+		return nil, fmt.Errorf("filter: 不允许的取值 %q", s)
+	}
+	return s, nil
+}
+
+// escapeLike 转义LIKE中的通配符，转义字符为!
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
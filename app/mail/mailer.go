@@ -0,0 +1,114 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/queue"
+)
+
+// Mailer 邮件发送接口
+type Mailer interface {
+	// Send 发送邮件，发件人为空时使用默认发件人
+	Send(ctx context.Context, msg *Message) error
+}
+
+// LogMailer 将邮件写入日志而不实际发送，用于开发环境
+type LogMailer struct {
+	logger *log.Logger
+	from   Address
+}
+
+// NewLogMailer 创建日志邮件驱动
+func NewLogMailer(logger *log.Logger, from Address) *LogMailer {
+	return &LogMailer{
+		logger: logger,
+		from:   from,
+	}
+}
+
+// Send 将邮件摘要和纯文本正文写入日志
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	msg = msg.withDefaults(m.from)
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	m.logger.Printf("[mail] from=%s to=%v cc=%v bcc=%v subject=%q attachments=%d\n%s",
+		msg.From, msg.To, msg.Cc, msg.Bcc, msg.Subject, len(msg.Attachments), msg.Text)
+	return nil
+}
+
+// FileMailer 将邮件保存为.eml文件，用于本地开发和测试
+type FileMailer struct {
+	dir  string
+	from Address
+}
+
+// NewFileMailer 创建文件邮件驱动，dir不存在时会自动创建
+func NewFileMailer(dir string, from Address) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+// Send 将邮件原文写入以时间命名的.eml文件
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	msg = msg.withDefaults(m.from)
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%d.eml", time.Now().Format("20060102-150405.000000"), os.Getpid())
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0644)
+}
+
+// SendJobType 异步发送邮件的队列任务类型
+const SendJobType = "mail:send"
+
+// QueuedMailer 通过后台队列异步发送邮件，避免请求阻塞在SMTP上
+type QueuedMailer struct {
+	mailer Mailer
+	queue  *queue.Queue
+}
+
+// NewQueuedMailer 创建队列邮件驱动，并在队列上注册实际发送邮件的处理器
+func NewQueuedMailer(mailer Mailer, q *queue.Queue) *QueuedMailer {
+	queue.Handle(q, SendJobType, func(ctx context.Context, msg Message) error {
+		return mailer.Send(ctx, &msg)
+	})
+
+	return &QueuedMailer{
+		mailer: mailer,
+		queue:  q,
+	}
+}
+
+// Send 将邮件投递到队列
+func (m *QueuedMailer) Send(ctx context.Context, msg *Message) error {
+	if len(msg.Recipients()) == 0 {
+		return ErrNoRecipients
+	}
+	if err := msg.validateHeaders(); err != nil {
+		return err
+	}
+	return m.queue.Dispatch(ctx, SendJobType, msg)
+}
+
+// SendNow 跳过队列立即发送
+func (m *QueuedMailer) SendNow(ctx context.Context, msg *Message) error {
+	return m.mailer.Send(ctx, msg)
+}
@@ -0,0 +1,301 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 定义错误
+var (
+	ErrNoRecipients  = errors.New("邮件没有收件人")
+	ErrNoSender      = errors.New("邮件没有发件人")
+	ErrInvalidHeader = errors.New("邮件头包含非法字符")
+)
+
+// Address 邮件地址
+type Address struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// String 返回符合RFC 5322的地址格式，名称中的非ASCII字符会被编码
+func (a Address) String() string {
+	return (&netmail.Address{Name: a.Name, Address: a.Address}).String()
+}
+
+// Attachment 邮件附件
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// Message 邮件内容，可同时包含纯文本和HTML正文以及附件
+type Message struct {
+	From        Address           `json:"from"`
+	To          []string          `json:"to"`
+	Cc          []string          `json:"cc,omitempty"`
+	Bcc         []string          `json:"bcc,omitempty"`
+	ReplyTo     string            `json:"reply_to,omitempty"`
+	Subject     string            `json:"subject"`
+	Text        string            `json:"text,omitempty"`
+	HTML        string            `json:"html,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// Attach 添加附件，未指定类型时根据文件扩展名推断
+func (m *Message) Attach(filename string, data []byte, contentType string) {
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(filename))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	m.Attachments = append(m.Attachments, Attachment{
+		Filename:    filename,
+		ContentType: contentType,
+		Data:        data,
+	})
+}
+
+// Recipients 返回所有收件人地址，包括抄送和密送
+func (m *Message) Recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	recipients = append(recipients, m.Bcc...)
+	return recipients
+}
+
+// withDefaults 返回填充了默认发件人的邮件副本
+func (m *Message) withDefaults(from Address) *Message {
+	msg := *m
+	if msg.From.Address == "" {
+		msg.From = from
+	}
+	return &msg
+}
+
+// validate 检查邮件是否可以发送
+func (m *Message) validate() error {
+	if m.From.Address == "" {
+		return ErrNoSender
+	}
+	if len(m.Recipients()) == 0 {
+		return ErrNoRecipients
+	}
+	return m.validateHeaders()
+}
+
+// validateHeaders 检查写入邮件头的地址和自定义邮件头，包含换行时可以伪造额外的邮件头或收件人
+func (m *Message) validateHeaders() error {
+	values := append([]string{m.From.Address, m.ReplyTo}, m.Recipients()...)
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w: %q", ErrInvalidHeader, value)
+		}
+	}
+	for key, value := range m.Headers {
+		if !validHeaderKey(key) || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w: %q", ErrInvalidHeader, key)
+		}
+	}
+	return nil
+}
+
+// validHeaderKey 邮件头名称只能由除冒号外的可打印ASCII字符组成
+func validHeaderKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] > '~' || key[i] == ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// Bytes 生成RFC 5322格式的邮件原文，密送地址不会出现在邮件头中
+// 结构为 multipart/mixed（有附件时）包含 multipart/alternative（同时有文本和HTML时）
+func (m *Message) Bytes() ([]byte, error) {
+	if err := m.validateHeaders(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", m.From.String())
+	header.Set("To", strings.Join(m.To, ", "))
+	if len(m.Cc) > 0 {
+		header.Set("Cc", strings.Join(m.Cc, ", "))
+	}
+	if m.ReplyTo != "" {
+		header.Set("Reply-To", m.ReplyTo)
+	}
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-ID", messageID(m.From.Address))
+	header.Set("MIME-Version", "1.0")
+	for key, value := range m.Headers {
+		header.Set(key, value)
+	}
+
+	bodyHeader, body, err := m.body()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Attachments) == 0 {
+		for key, values := range bodyHeader {
+			header[key] = values
+		}
+		writeHeader(&buf, header)
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}
+
+	// multipart.Writer在创建第一个部分前不会写入内容，因此可以先写邮件头
+	mixed := multipart.NewWriter(&buf)
+	header.Set("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	writeHeader(&buf, header)
+
+	part, err := mixed.CreatePart(bodyHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(body); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range m.Attachments {
+		if err := writeAttachment(mixed, attachment); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// body 生成正文部分的头部和编码后的内容
+// 同时有纯文本和HTML时生成multipart/alternative
+func (m *Message) body() (textproto.MIMEHeader, []byte, error) {
+	header := textproto.MIMEHeader{}
+	var buf bytes.Buffer
+
+	if m.Text != "" && m.HTML != "" {
+		alternative := multipart.NewWriter(&buf)
+		if err := writeTextPart(alternative, "text/plain", m.Text); err != nil {
+			return nil, nil, err
+		}
+		if err := writeTextPart(alternative, "text/html", m.HTML); err != nil {
+			return nil, nil, err
+		}
+		if err := alternative.Close(); err != nil {
+			return nil, nil, err
+		}
+		header.Set("Content-Type", "multipart/alternative; boundary="+alternative.Boundary())
+		return header, buf.Bytes(), nil
+	}
+
+	contentType, content := "text/plain", m.Text
+	if m.HTML != "" {
+		contentType, content = "text/html", m.HTML
+	}
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	if err := writeQuotedPrintable(&buf, content); err != nil {
+		return nil, nil, err
+	}
+	return header, buf.Bytes(), nil
+}
+
+// writeTextPart 写入multipart中的文本部分
+func writeTextPart(w *multipart.Writer, contentType, content string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	return writeQuotedPrintable(part, content)
+}
+
+// writeAttachment 以base64编码写入附件
+func writeAttachment(w *multipart.Writer, attachment Attachment) error {
+	header := textproto.MIMEHeader{}
+	mediaType, params, err := mime.ParseMediaType(attachment.ContentType)
+	if err != nil {
+		mediaType, params = "application/octet-stream", map[string]string{}
+	}
+	params["name"] = attachment.Filename
+	// FormatMediaType会按RFC 2231编码非ASCII文件名
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	header.Set("Content-Transfer-Encoding", "base64")
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	// 按RFC 2045每行不超过76个字符
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
+
+// writeQuotedPrintable 以quoted-printable编码写入内容
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, content); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeHeader 写入邮件头和分隔空行
+func writeHeader(w io.Writer, header textproto.MIMEHeader) {
+	for key, values := range header {
+		for _, value := range values {
+			fmt.Fprintf(w, "%s: %s\r\n", key, value)
+		}
+	}
+	io.WriteString(w, "\r\n")
+}
+
+// messageID 生成唯一的Message-ID
+func messageID(from string) string {
+	domain := "localhost"
+	if _, host, ok := strings.Cut(from, "@"); ok {
+		domain = host
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
@@ -0,0 +1,143 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTP加密方式
+const (
+	EncryptionNone     = "none"
+	EncryptionSTARTTLS = "tls" // 明文连接后通过STARTTLS升级，通常使用587端口
+	EncryptionSSL      = "ssl" // 隐式TLS，连接建立即加密，通常使用465端口
+)
+
+// SMTPConfig SMTP驱动配置
+type SMTPConfig struct {
+	Host       string
+	Port       string
+	Username   string
+	Password   string
+	Encryption string
+	Timeout    time.Duration
+	From       Address
+}
+
+// SMTPMailer 通过SMTP服务器发送邮件
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+// NewSMTPMailer 创建SMTP邮件驱动，加密方式为空时不加密，不支持的加密方式返回错误，
+// 避免配置拼写错误时以明文发送认证信息
+func NewSMTPMailer(config SMTPConfig) (*SMTPMailer, error) {
+	switch config.Encryption {
+	case "":
+		config.Encryption = EncryptionNone
+	case EncryptionNone, EncryptionSTARTTLS, EncryptionSSL:
+	default:
+		return nil, fmt.Errorf("不支持的SMTP加密方式: %q，可选值为 %s、%s、%s", config.Encryption, EncryptionNone, EncryptionSTARTTLS, EncryptionSSL)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &SMTPMailer{config: config}, nil
+}
+
+// Send 发送邮件
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	msg = msg.withDefaults(m.config.From)
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	client, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if m.config.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("SMTP服务器不支持认证")
+		}
+		// PlainAuth只允许在TLS连接或本地连接上发送密码
+		if err := client.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)); err != nil {
+			return fmt.Errorf("SMTP认证失败: %w", err)
+		}
+	}
+
+	if err := client.Mail(msg.From.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range msg.Recipients() {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("收件人 %s 被拒绝: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial 连接SMTP服务器并按配置建立加密连接
+func (m *SMTPMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+	tlsConfig := &tls.Config{ServerName: m.config.Host}
+	dialer := &net.Dialer{Timeout: m.config.Timeout}
+
+	var conn net.Conn
+	var err error
+	if m.config.Encryption == EncryptionSSL {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("连接SMTP服务器失败: %w", err)
+	}
+
+	// 整个会话使用同一个截止时间，避免服务器无响应时永久阻塞
+	deadline := time.Now().Add(m.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if m.config.Encryption == EncryptionSTARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, errors.New("SMTP服务器不支持STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("STARTTLS失败: %w", err)
+		}
+	}
+	return client, nil
+}
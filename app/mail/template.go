@@ -0,0 +1,281 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// DefaultTemplates 返回内置的邮件模板
+//
+// 目录结构：
+//
+//	layouts/  公共布局，定义 "layout"
+//	partials/ 公共片段，如 "button"、"footer"
+//	<locale>/ 各语言的模板，<name>.txt 定义 "subject" 和纯文本 "content"，
+//	          <name>.html 定义HTML "content"（可选），.tmpl 文件为该语言共享的片段
+func DefaultTemplates() fs.FS {
+	sub, _ := fs.Sub(embedded, "templates")
+	return sub
+}
+
+// ErrTemplateNotFound 模板不存在
+var ErrTemplateNotFound = errors.New("邮件模板不存在")
+
+// App 模板中可用的应用信息
+type App struct {
+	Name string
+	URL  string
+}
+
+// templateData 传递给模板的数据，模板中通过 .App、.Locale、.Subject 和 .Data 访问
+type templateData struct {
+	App     App
+	Locale  string
+	Subject string
+	Data    interface{}
+}
+
+// localeTemplates 某个语言下的一组模板
+type localeTemplates struct {
+	locale string
+	text   map[string]*texttemplate.Template
+	html   map[string]*htmltemplate.Template
+}
+
+// Renderer 邮件模板渲染器
+type Renderer struct {
+	app           App
+	defaultLocale string
+	locales       map[string]*localeTemplates // 键为小写的语言代码
+}
+
+// templateFuncs 模板中可用的函数
+var templateFuncs = map[string]interface{}{
+	"dict": dict,
+}
+
+// NewRenderer 从文件系统加载并解析所有模板，目录结构见DefaultTemplates
+// 模板语法错误会在此时返回，而不是在发送邮件时
+func NewRenderer(fsys fs.FS, app App, defaultLocale string) (*Renderer, error) {
+	r := &Renderer{
+		app:           app,
+		defaultLocale: defaultLocale,
+		locales:       make(map[string]*localeTemplates),
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == "layouts" || entry.Name() == "partials" {
+			continue
+		}
+		templates, err := parseLocale(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		r.locales[strings.ToLower(entry.Name())] = templates
+	}
+
+	if r.resolve(defaultLocale) == nil {
+		return nil, fmt.Errorf("默认语言 %s 没有邮件模板", defaultLocale)
+	}
+	return r, nil
+}
+
+// parseLocale 解析某个语言目录下的模板，每个模板都包含公共布局、片段和该语言的共享片段
+func parseLocale(fsys fs.FS, locale string) (*localeTemplates, error) {
+	shared, err := fs.Glob(fsys, path.Join(locale, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+
+	textBase := texttemplate.New("").Funcs(templateFuncs).Option("missingkey=error")
+	if textBase, err = parseFiles(textBase, fsys, "layouts/*.txt", "partials/*.txt"); err != nil {
+		return nil, err
+	}
+	if len(shared) > 0 {
+		if textBase, err = textBase.ParseFS(fsys, shared...); err != nil {
+			return nil, err
+		}
+	}
+
+	htmlBase := htmltemplate.New("").Funcs(templateFuncs).Option("missingkey=error")
+	if htmlBase, err = parseHTMLFiles(htmlBase, fsys, "layouts/*.html", "partials/*.html"); err != nil {
+		return nil, err
+	}
+	if len(shared) > 0 {
+		if htmlBase, err = htmlBase.ParseFS(fsys, shared...); err != nil {
+			return nil, err
+		}
+	}
+
+	templates := &localeTemplates{
+		locale: locale,
+		text:   make(map[string]*texttemplate.Template),
+		html:   make(map[string]*htmltemplate.Template),
+	}
+
+	files, err := fs.Glob(fsys, path.Join(locale, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".txt")
+		t, err := texttemplate.Must(textBase.Clone()).ParseFS(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		if t.Lookup("subject") == nil || t.Lookup("content") == nil {
+			return nil, fmt.Errorf("邮件模板 %s 必须定义 subject 和 content", file)
+		}
+		templates.text[name] = t
+
+		htmlFile := path.Join(locale, name+".html")
+		if _, err := fs.Stat(fsys, htmlFile); err != nil {
+			continue
+		}
+		h, err := htmltemplate.Must(htmlBase.Clone()).ParseFS(fsys, htmlFile)
+		if err != nil {
+			return nil, err
+		}
+		templates.html[name] = h
+	}
+	return templates, nil
+}
+
+// parseFiles 解析匹配的纯文本模板文件，忽略没有匹配文件的模式
+func parseFiles(t *texttemplate.Template, fsys fs.FS, patterns ...string) (*texttemplate.Template, error) {
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if t, err = t.ParseFS(fsys, matches...); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// parseHTMLFiles 解析匹配的HTML模板文件，忽略没有匹配文件的模式
+func parseHTMLFiles(t *htmltemplate.Template, fsys fs.FS, patterns ...string) (*htmltemplate.Template, error) {
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if t, err = t.ParseFS(fsys, matches...); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Render 按语言渲染模板，返回填好主题和正文的邮件，收件人由调用方设置
+// 找不到对应语言时依次回退到主语言（如 en-US 回退到 en）和默认语言
+func (r *Renderer) Render(name, locale string, data interface{}) (*Message, error) {
+	templates := r.resolve(locale)
+	if templates == nil || templates.text[name] == nil {
+		templates = r.resolve(r.defaultLocale)
+	}
+	text, ok := templates.text[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	td := templateData{
+		App:    r.app,
+		Locale: templates.locale,
+		Data:   data,
+	}
+
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", td); err != nil {
+		return nil, err
+	}
+	td.Subject = strings.TrimSpace(subject.String())
+	if err := text.ExecuteTemplate(&body, "layout", td); err != nil {
+		return nil, err
+	}
+	msg := &Message{
+		Subject: td.Subject,
+		Text:    strings.TrimSpace(body.String()) + "\n",
+	}
+
+	if html, ok := templates.html[name]; ok {
+		var buf bytes.Buffer
+		if err := html.ExecuteTemplate(&buf, "layout", td); err != nil {
+			return nil, err
+		}
+		msg.HTML = buf.String()
+	}
+	return msg, nil
+}
+
+// Names 返回默认语言下所有模板名称
+func (r *Renderer) Names() []string {
+	templates := r.resolve(r.defaultLocale)
+	names := make([]string, 0, len(templates.text))
+	for name := range templates.text {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locales 返回所有支持的语言
+func (r *Renderer) Locales() []string {
+	locales := make([]string, 0, len(r.locales))
+	for _, templates := range r.locales {
+		locales = append(locales, templates.locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// resolve 查找语言对应的模板，先精确匹配再匹配主语言
+func (r *Renderer) resolve(locale string) *localeTemplates {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	if templates, ok := r.locales[locale]; ok {
+		return templates
+	}
+	if base, _, ok := strings.Cut(locale, "-"); ok {
+		return r.locales[base]
+	}
+	return nil
+}
+
+// dict 在模板中构造map，用于向片段传递多个参数
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict 参数必须成对出现")
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, errors.New("dict 的键必须是字符串")
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/service"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ctx.Locals 中保存当前用户的键
+const (
+	LocalsUser   = "user"
+	LocalsUserID = "user_id"
+	LocalsAPIKey = "api_key" // 使用API密钥认证时保存当前密钥
+)
+
+// APIKeyHeader 传递API密钥的请求头
+const APIKeyHeader = "X-API-Key"
+
+// JWTAuth 验证请求头中的JWT令牌，并将当前用户写入ctx.Locals
+func JWTAuth() fiber.Handler {
+	cfg := config.Load()
+	authService := service.NewAuthService()
+
+	return func(c *fiber.Ctx) error {
+		header := c.Get(cfg.JWT.HeaderName)
+		prefix := cfg.JWT.HeaderPrefix + " "
+		if !strings.HasPrefix(header, prefix) {
+			return config.UnauthorizedError(c)
+		}
+
+		user, err := authService.Authenticate(c.UserContext(), strings.TrimPrefix(header, prefix))
+		if err != nil {
+			return config.UnauthorizedError(c)
+		}
+
+		c.Locals(LocalsUser, user)
+		c.Locals(LocalsUserID, user.ID)
+		return c.Next()
+	}
+}
+
+// Authenticate 验证JWT令牌或X-API-Key请求头中的API密钥，两种方式写入相同的ctx.Locals
+// 使用API密钥时，GET和HEAD请求需要read权限，其他请求需要write权限
+func Authenticate() fiber.Handler {
+	jwtAuth := JWTAuth()
+	apiKeyService := service.NewAPIKeyService()
+
+	return func(c *fiber.Ctx) error {
+		plain := c.Get(APIKeyHeader)
+		if plain == "" {
+			return jwtAuth(c)
+		}
+
+		user, key, err := apiKeyService.Authenticate(c.UserContext(), plain)
+		if err != nil {
+			return config.UnauthorizedError(c)
+		}
+		if !key.HasScope(requiredScope(c.Method())) {
+			return config.Error(c, fiber.StatusForbidden, "API密钥权限不足")
+		}
+
+		c.Locals(LocalsUser, user)
+		c.Locals(LocalsUserID, user.ID)
+		c.Locals(LocalsAPIKey, key)
+		return c.Next()
+	}
+}
+
+// OptionalAuth 请求携带JWT令牌或API密钥时按Authenticate认证，未携带时以匿名身份继续
+// 用于公开接口根据当前用户输出不同内容，凭证无效时仍返回401
+func OptionalAuth() fiber.Handler {
+	cfg := config.Load()
+	authenticate := Authenticate()
+
+	return func(c *fiber.Ctx) error {
+		if c.Get(APIKeyHeader) == "" && c.Get(cfg.JWT.HeaderName) == "" {
+			return c.Next()
+		}
+		return authenticate(c)
+	}
+}
+
+// requiredScope 返回请求方法所需的API密钥权限
+func requiredScope(method string) string {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return model.APIKeyScopeRead
+	}
+	return model.APIKeyScopeWrite
+}
+
+// RequireRole 要求当前用户具有指定角色之一，需在JWTAuth之后使用
+// 启用TwoFactorRequiredAdmin策略时，管理员必须先启用两步验证才能使用管理员权限
+func RequireRole(roles ...string) fiber.Handler {
+	cfg := config.Load()
+
+	return func(c *fiber.Ctx) error {
+		user := CurrentUser(c)
+		if user == nil {
+			return config.UnauthorizedError(c)
+		}
+
+		for _, role := range roles {
+			if user.Role != role {
+				continue
+			}
+			if role == model.RoleAdmin && cfg.Security.TwoFactorRequiredAdmin && !user.IsTwoFactorEnabled() {
+				return config.Error(c, fiber.StatusForbidden, "管理员账号必须启用两步验证")
+			}
+			return c.Next()
+		}
+		return config.ForbiddenError(c)
+	}
+}
+
+// CurrentUser 返回当前请求的已认证用户，未认证时返回nil
+func CurrentUser(c *fiber.Ctx) *model.User {
+	user, _ := c.Locals(LocalsUser).(*model.User)
+	return user
+}
+
+// CurrentAPIKey 返回当前请求使用的API密钥，使用JWT认证时返回nil
+func CurrentAPIKey(c *fiber.Ctx) *model.APIKey {
+	key, _ := c.Locals(LocalsAPIKey).(*model.APIKey)
+	return key
+}
+
+// RequireVerified 要求当前用户已验证邮箱，需在JWTAuth或Authenticate之后使用
+// 用于上传文件、创建API密钥等需要可联系邮箱的操作，可加在分组或单个路由上
+func RequireVerified() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := CurrentUser(c)
+		if user == nil {
+			return config.UnauthorizedError(c)
+		}
+		if !user.IsEmailVerified() {
+			return config.Error(c, fiber.StatusForbidden, "请先验证邮箱")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CacheConfig 响应缓存配置
+type CacheConfig struct {
+	TTL  time.Duration // 缓存时间，0表示使用缓存的默认过期时间
+	Tags []string      // 缓存标签，通常为接口读取的表名，表数据变更时自动失效
+}
+
+// cachedResponse 缓存的响应
+type cachedResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Link        string `json:"link,omitempty"` // 分页接口的Link响应头
+	Body        []byte `json:"body"`
+}
+
+// etagTable 与fiber的etag中间件使用相同的CRC多项式，生成的ETag与之前保持一致
+var etagTable = crc32.MakeTable(0xD5828281)
+
+// SetupETag 为GET请求的响应生成ETag，并对If-None-Match请求返回304
+// 文件下载等以流的形式发送的响应不读取响应体，避免整个文件被读入内存；处理函数已设置ETag时不覆盖
+// fiber的etag中间件在检查这两种情况之前就读取了响应体，因此不能直接使用
+func SetupETag(app *fiber.App) {
+	app.Use(func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet {
+			return c.Next()
+		}
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		resp := c.Response()
+		if resp.StatusCode() != fiber.StatusOK || resp.IsBodyStream() || len(resp.Header.Peek(fiber.HeaderETag)) > 0 {
+			return nil
+		}
+		body := resp.Body()
+		if len(body) == 0 {
+			return nil
+		}
+
+		tag := `"` + strconv.Itoa(len(body)) + "-" + strconv.FormatUint(uint64(crc32.Checksum(body, etagTable)), 10) + `"`
+		if ifNoneMatch(c.Get(fiber.HeaderIfNoneMatch), tag) {
+			c.Context().ResetBody()
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+		c.Set(fiber.HeaderETag, tag)
+		return nil
+	})
+}
+
+// ifNoneMatch 判断If-None-Match是否包含指定的ETag，使用弱比较
+func ifNoneMatch(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == tag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// ResponseCache 在服务端缓存GET请求的响应
+// 缓存键区分请求路径、查询参数和当前用户，需在JWTAuth之后使用才能区分用户
+func ResponseCache(cacheConfig CacheConfig) fiber.Handler {
+	store := config.GetCache()
+
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet || strings.Contains(c.Get(fiber.HeaderCacheControl), "no-cache") {
+			return c.Next()
+		}
+
+		// 响应与用户相关，仅允许客户端私有缓存并在使用前通过ETag校验
+		c.Set(fiber.HeaderCacheControl, "private, no-cache")
+
+		key := responseCacheKey(c)
+		var cached cachedResponse
+		if err := store.Get(c.UserContext(), key, &cached); err == nil {
+			c.Set("X-Cache", "HIT")
+			c.Set(fiber.HeaderContentType, cached.ContentType)
+			if cached.Link != "" {
+				c.Set(fiber.HeaderLink, cached.Link)
+			}
+			return c.Status(cached.Status).Send(cached.Body)
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		c.Set("X-Cache", "MISS")
+
+		if c.Response().StatusCode() != fiber.StatusOK {
+			return nil
+		}
+
+		cached = cachedResponse{
+			Status:      fiber.StatusOK,
+			ContentType: string(c.Response().Header.ContentType()),
+			Link:        c.GetRespHeader(fiber.HeaderLink),
+			Body:        append([]byte(nil), c.Response().Body()...),
+		}
+		// 写入失败不影响本次响应
+		_ = store.Set(c.UserContext(), key, cached, cacheConfig.TTL, cacheConfig.Tags...)
+		return nil
+	}
+}
+
+// responseCacheKey 根据请求路径、排序后的查询参数和当前用户生成缓存键
+func responseCacheKey(c *fiber.Ctx) string {
+	var params []string
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		params = append(params, string(key)+"="+string(value))
+	})
+	sort.Strings(params)
+
+	user := "guest"
+	if userID, ok := c.Locals(LocalsUserID).(uint); ok {
+		user = fmt.Sprint(userID)
+	}
+
+	sum := sha256.Sum256([]byte(c.Path() + "?" + strings.Join(params, "&") + "#" + user))
+	return "responses:" + hex.EncodeToString(sum[:])
+}
@@ -15,4 +15,7 @@ func Setup(app *fiber.App) {
 
 	// 日志中间件
 	SetupLogger(app)
+
+	// ETag中间件
+	SetupETag(app)
 }
@@ -14,8 +14,9 @@ var (
 	// CORS配置
 	corsConfig = cors.Config{
 		AllowOrigins:     "*",
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-API-Key, Upload-Offset, Upload-Checksum",
+		ExposeHeaders:    "Location, Upload-Offset, Upload-Length",
 		AllowCredentials: false,
 		MaxAge:           300,
 	}
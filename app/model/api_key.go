@@ -0,0 +1,73 @@
+package model
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// API密钥权限范围
+const (
+	APIKeyScopeRead  = "read"  // 只读请求（GET、HEAD）
+	APIKeyScopeWrite = "write" // 写入请求（POST、PUT、DELETE等）
+)
+
+// APIKeyScopes API密钥权限范围列表，在数据库中以空格分隔保存
+type APIKeyScopes []string
+
+// Value 实现driver.Valuer接口
+func (s APIKeyScopes) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
+
+// Scan 实现sql.Scanner接口
+func (s *APIKeyScopes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		*s = strings.Fields(v)
+	case []byte:
+		*s = strings.Fields(string(v))
+	case nil:
+		*s = nil
+	default:
+		return fmt.Errorf("无法将 %T 转换为APIKeyScopes", value)
+	}
+	return nil
+}
+
+// APIKey 用户的个人API密钥，供脚本和CI等机器客户端使用
+// 只保存密钥的SHA-256哈希，明文密钥只在创建时返回一次，Prefix用于识别密钥和查找记录
+// @Description 个人API密钥
+type APIKey struct {
+	ID         uint64       `json:"id" gorm:"primaryKey"`
+	UserID     uint         `json:"user_id" gorm:"not null;index"`
+	Name       string       `json:"name" gorm:"size:100;not null"`
+	Prefix     string       `json:"prefix" gorm:"size:20;not null;uniqueIndex"`
+	KeyHash    string       `json:"-" gorm:"size:64;not null"`
+	Scopes     APIKeyScopes `json:"scopes" gorm:"type:varchar(255);not null"`
+	ExpiresAt  *time.Time   `json:"expires_at"` // 为空表示永不过期
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// IsExpired 判断密钥是否已过期
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
+
+// HasScope 判断密钥是否具有指定权限
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
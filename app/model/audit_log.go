@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// 审计事件类型
+const (
+	AuditRegistered      = "account.registered"
+	AuditLoginSucceeded  = "login.succeeded"
+	AuditLoginFailed     = "login.failed"
+	AuditLoginLocked     = "login.locked"     // 账号被临时锁定
+	AuditLoginBlocked    = "login.blocked"    // 锁定期间或IP超限时的登录被拒绝
+	AuditAccountUnlock   = "account.unlocked" // 管理员解除锁定
+	AuditTwoFactorOn     = "2fa.enabled"
+	AuditTwoFactorOff    = "2fa.disabled"
+	AuditRecoveryUsed    = "2fa.recovery_used" // 使用恢复码登录
+	AuditTwoFactorFailed = "2fa.failed"        // 关闭两步验证或重新生成恢复码时密码或验证码错误
+	AuditAPIKeyCreated   = "api_key.created"
+	AuditAPIKeyRevoked   = "api_key.revoked"
+	AuditIdentityLinked  = "identity.linked" // 第三方身份关联到已有用户
+)
+
+// AuditLog 安全审计日志，只追加不修改
+// @Description 安全审计日志
+type AuditLog struct {
+	ID        uint64    `json:"id" gorm:"primaryKey"`
+	UserID    *uint     `json:"user_id" gorm:"index"`
+	ActorID   *uint     `json:"actor_id"` // 执行操作的用户，与UserID不同时表示管理员操作
+	Action    string    `json:"action" gorm:"size:50;not null;index"`
+	IP        string    `json:"ip" gorm:"size:45;index"`
+	UserAgent string    `json:"user_agent" gorm:"size:255"`
+	Details   string    `json:"details" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
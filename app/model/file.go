@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// File 用户上传的文件
+// 文件按内容的SHA-256哈希保存，内容相同的文件共用同一个存储对象
+// @Description 上传文件
+type File struct {
+	ID        uint64    `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Name      string    `json:"name" gorm:"size:255;not null"` // 上传时的原始文件名
+	MimeType  string    `json:"mime_type" gorm:"size:100;not null"`
+	Size      int64     `json:"size" gorm:"not null"`
+	Checksum  string    `json:"checksum" gorm:"size:64;not null"` // 内容的SHA-256哈希
+	Disk      string    `json:"-" gorm:"size:20;not null"`        // 保存文件的存储驱动
+	Path      string    `json:"-" gorm:"size:255;not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (File) TableName() string {
+	return "files"
+}
+
+// FileContent 存储中的一份文件内容，保存内容和释放最后一个引用时对该行加锁
+// 保证另一个请求复用已存在的存储对象时，该对象不会被同时删除；对象删除后该行保留，供再次上传相同内容时加锁
+type FileContent struct {
+	Disk      string `gorm:"primaryKey;size:20"`
+	Path      string `gorm:"primaryKey;size:255"`
+	CreatedAt time.Time
+}
+
+// TableName 指定表名
+func (FileContent) TableName() string {
+	return "file_contents"
+}
+
+// DownloadNonce 已使用的一次性下载链接，以链接中的随机值为主键，插入冲突说明链接已被使用
+// 记录在链接过期后不再需要，由定时任务清理
+type DownloadNonce struct {
+	Nonce     string    `gorm:"primaryKey;size:32"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+// TableName 指定表名
+func (DownloadNonce) TableName() string {
+	return "download_nonces"
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// 队列任务状态
+const (
+	QueueJobPending = "pending"
+	QueueJobRunning = "running"
+	QueueJobDead    = "dead"
+)
+
+// QueueJob 后台队列任务
+// 执行成功的任务会被删除，超过最大尝试次数的任务保留为dead状态以便排查和重新投递
+// @Description 后台队列任务
+type QueueJob struct {
+	ID          uint64     `json:"id" gorm:"primaryKey"`
+	Queue       string     `json:"queue" gorm:"size:50;not null;index:idx_queue_jobs_fetch,priority:1"`
+	Type        string     `json:"type" gorm:"size:100;not null"`
+	Payload     string     `json:"payload"` // 不指定类型，MySQL中为longtext，邮件任务的载荷包含正文和附件，可能超过text的64KB上限
+	Status      string     `json:"status" gorm:"size:20;not null;index:idx_queue_jobs_fetch,priority:2"`
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int        `json:"max_attempts" gorm:"not null;default:1"`
+	AvailableAt time.Time  `json:"available_at" gorm:"not null;index:idx_queue_jobs_fetch,priority:3"`
+	ReservedAt  *time.Time `json:"reserved_at"`
+	LastError   string     `json:"last_error" gorm:"type:text"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (QueueJob) TableName() string {
+	return "queue_jobs"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// RecoveryCode 两步验证恢复码，每个只能使用一次，只保存SHA-256哈希
+// @Description 两步验证恢复码
+type RecoveryCode struct {
+	ID        uint64     `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
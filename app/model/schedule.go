@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// 定时任务触发方式
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+)
+
+// 定时任务执行状态
+const (
+	JobRunRunning = "running"
+	JobRunSuccess = "success"
+	JobRunFailed  = "failed"
+)
+
+// ScheduleLock 定时任务分布式锁
+// 每行代表一个任务的租约，持有者需在过期前续约
+type ScheduleLock struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:100"`
+	Owner     string    `json:"owner" gorm:"size:100;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ScheduleLock) TableName() string {
+	return "schedule_locks"
+}
+
+// ScheduleJob 定时任务的运行时状态，在所有节点间共享
+type ScheduleJob struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:100"`
+	Paused    bool      `json:"paused" gorm:"not null;default:false"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ScheduleJob) TableName() string {
+	return "schedule_jobs"
+}
+
+// JobRun 定时任务的执行记录
+// @Description 定时任务执行记录
+type JobRun struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Job        string     `json:"job" gorm:"size:100;not null;index:idx_job_runs_job_started,priority:1"`
+	Trigger    string     `json:"trigger" gorm:"size:20;not null"`
+	Status     string     `json:"status" gorm:"size:20;not null;index"`
+	Error      string     `json:"error" gorm:"type:text"`
+	Node       string     `json:"node" gorm:"size:100"`
+	StartedAt  time.Time  `json:"started_at" gorm:"not null;index:idx_job_runs_job_started,priority:2;index"`
+	FinishedAt *time.Time `json:"finished_at"`
+	DurationMs int64      `json:"duration_ms"`
+}
+
+// TableName 指定表名
+func (JobRun) TableName() string {
+	return "job_runs"
+}
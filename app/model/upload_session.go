@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// UploadSessionFilePrefix 分片上传临时文件的文件名前缀，后接会话ID
+const UploadSessionFilePrefix = "chunked-"
+
+// UploadSession 分片上传会话
+// 分片按顺序追加到临时目录中的文件，上传完成后保存为普通文件记录
+// @Description 分片上传会话
+type UploadSession struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:32"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Name      string    `json:"name" gorm:"size:255;not null"` // 上传时的原始文件名
+	Size      int64     `json:"size" gorm:"not null"`          // 文件总大小
+	Offset    int64     `json:"offset" gorm:"column:upload_offset;not null;default:0"`
+	Checksum  string    `json:"checksum,omitempty" gorm:"size:64"` // 客户端声明的整个文件的SHA-256哈希，完成上传时校验
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UploadSession) TableName() string {
+	return "upload_sessions"
+}
+
+// IsExpired 会话是否已过期
+func (s *UploadSession) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
+
+// IsComplete 是否已收到全部内容
+func (s *UploadSession) IsComplete() bool {
+	return s.Offset == s.Size
+}
+
+// Progress 上传进度，取值0到1
+func (s *UploadSession) Progress() float64 {
+	if s.Size == 0 {
+		return 0
+	}
+	return float64(s.Offset) / float64(s.Size)
+}
+
+// FileName 临时文件的文件名
+func (s *UploadSession) FileName() string {
+	return UploadSessionFilePrefix + s.ID
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -28,6 +30,23 @@ type User struct {
 	Avatar   string `json:"avatar" gorm:"size:255"`
 	Role     string `json:"role" gorm:"size:20;default:user" validate:"oneof=admin user guest"`
 	IsActive int    `json:"is_active" gorm:"default:1" validate:"oneof=0 1"`
+	Locale   string `json:"locale" gorm:"size:20" validate:"omitempty,max=20"` // 邮件等通知使用的语言，为空时使用应用默认语言
+
+	// EmailVerifiedAt 邮箱验证时间，为空表示邮箱尚未验证
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// TokenVersion 令牌版本，递增后此前签发的所有JWT令牌失效
+	TokenVersion int `json:"-" gorm:"not null;default:0"`
+
+	// 登录锁定状态
+	FailedLoginCount int        `json:"-" gorm:"not null;default:0"` // 连续登录失败次数，锁定或登录成功后清零
+	LockoutCount     int        `json:"-" gorm:"not null;default:0"` // 连续锁定次数，决定下次锁定时长
+	LockedUntil      *time.Time `json:"-"`
+
+	// 两步验证，TOTPSecret在确认启用前也会保存，以TwoFactorEnabledAt判断是否已启用
+	TOTPSecret         string     `json:"-" gorm:"column:totp_secret;size:64"`
+	TOTPLastStep       int64      `json:"-" gorm:"column:totp_last_step;not null;default:0"` // 最近一次使用的时间步，防止验证码重放
+	TwoFactorEnabledAt *time.Time `json:"two_factor_enabled_at"`
 }
 
 // TableName 指定表名
@@ -45,16 +64,13 @@ func (u *User) BeforeUpdate(tx *gorm.DB) error {
 	return u.hashPasswordIfNeeded()
 }
 
-// hashPasswordIfNeeded 如果密码未哈希则进行哈希处理
+// hashPasswordIfNeeded 兼容直接给Password赋明文的旧代码，保存时哈希不是bcrypt哈希的密码
+// 设置新密码应使用SetPassword，不依赖这里的判断
 func (u *User) hashPasswordIfNeeded() error {
-	// 如果密码未被哈希处理，则进行哈希处理
-	if len(u.Password) < 60 { // bcrypt哈希通常长度为60
-		// 使用bcrypt进行哈希
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
-		if err != nil {
+	if _, err := bcrypt.Cost([]byte(u.Password)); err != nil {
+		if err := u.SetPassword(u.Password); err != nil {
 			return err
 		}
-		u.Password = string(hashedPassword)
 	}
 
 	// 设置默认角色
@@ -76,22 +92,32 @@ func (u *User) CheckPassword(password string) bool {
 	return err == nil
 }
 
+// SetPassword 哈希并设置密码，密码超过bcrypt的72字节上限时返回错误
+func (u *User) SetPassword(password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.Password = string(hashedPassword)
+	return nil
+}
+
 // ChangePassword 更改用户密码
 func (u *User) ChangePassword(newPassword string) error {
-	u.Password = newPassword
-	return u.hashPasswordIfNeeded()
+	return u.SetPassword(newPassword)
 }
 
-// SanitizeOutput 清理敏感数据用于输出
-func (u *User) SanitizeOutput() map[string]interface{} {
-	return map[string]interface{}{
-		"id":         u.ID,
-		"username":   u.Username,
-		"email":      u.Email,
-		"avatar":     u.Avatar,
-		"role":       u.Role,
-		"is_active":  u.IsActive,
-		"created_at": u.CreatedAt,
-		"updated_at": u.UpdatedAt,
-	}
+// IsEmailVerified 邮箱是否已验证
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// IsLocked 账号是否处于登录锁定期
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
+// IsTwoFactorEnabled 是否已启用两步验证
+func (u *User) IsTwoFactorEnabled() bool {
+	return u.TwoFactorEnabledAt != nil
 }
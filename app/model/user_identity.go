@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// UserIdentity 用户在第三方身份提供方的身份，一个用户可以关联多个提供方
+// Provider和Subject唯一确定一个外部身份，邮箱变化不影响关联
+// @Description 第三方登录身份
+type UserIdentity struct {
+	ID          uint64     `json:"id" gorm:"primaryKey"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Provider    string     `json:"provider" gorm:"size:50;not null;uniqueIndex:idx_user_identities_subject,priority:1"`
+	Subject     string     `json:"subject" gorm:"size:255;not null;uniqueIndex:idx_user_identities_subject,priority:2"`
+	Email       string     `json:"email" gorm:"size:100"` // 最近一次登录时身份提供方返回的邮箱
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
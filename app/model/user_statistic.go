@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// UserStatistic 每日用户统计数据，以日期为主键，重复计算会覆盖同一行
+// @Description 每日用户统计
+type UserStatistic struct {
+	Date          time.Time `json:"date" gorm:"primaryKey;type:date"`
+	NewUsers      int64     `json:"new_users" gorm:"not null;default:0"`
+	TotalUsers    int64     `json:"total_users" gorm:"not null;default:0"`
+	ActiveUsers   int64     `json:"active_users" gorm:"not null;default:0"`
+	InactiveUsers int64     `json:"inactive_users" gorm:"not null;default:0"`
+	AdminUsers    int64     `json:"admin_users" gorm:"not null;default:0"`
+	RegularUsers  int64     `json:"regular_users" gorm:"not null;default:0"`
+	GuestUsers    int64     `json:"guest_users" gorm:"not null;default:0"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserStatistic) TableName() string {
+	return "user_statistics"
+}
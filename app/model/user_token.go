@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// 用户令牌用途
+const (
+	TokenPurposePasswordReset = "password_reset"
+	TokenPurposeVerifyEmail   = "verify_email"
+)
+
+// UserToken 一次性用户令牌，用于重置密码等需要通过邮件链接确认的操作
+// 只保存令牌的SHA-256哈希，明文令牌只出现在发给用户的链接中
+// @Description 一次性用户令牌
+type UserToken struct {
+	ID        uint64     `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;index:idx_user_tokens_user,priority:1"`
+	Purpose   string     `json:"purpose" gorm:"size:30;not null;index:idx_user_tokens_user,priority:2"`
+	TokenHash string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	Email     string     `json:"email" gorm:"size:100"` // 令牌签发时的目标邮箱
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
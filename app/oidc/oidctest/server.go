@@ -0,0 +1,185 @@
+// Package oidctest 提供用于测试的OpenID Connect身份提供方
+// 服务发现文档、JWKS和令牌端点，使用授权码模式并校验PKCE
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyID 签名公钥的kid
+const KeyID = "test-key"
+
+// Server 测试用身份提供方
+type Server struct {
+	*httptest.Server
+	Key *rsa.PrivateKey
+
+	mu        sync.Mutex
+	codes     map[string]grant
+	tokenForm url.Values
+}
+
+// grant 已签发但尚未换取的授权码
+type grant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+// NewServer 启动测试用身份提供方，测试结束时自动关闭
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成签名密钥失败: %v", err)
+	}
+	s := &Server{Key: key, codes: make(map[string]grant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Authorize 模拟用户在授权地址完成登录，返回授权码和state
+// ID令牌默认包含iss、aud、sub、iat、exp和授权请求中的nonce，claims中的值会覆盖默认值，值为nil时删除该声明
+func (s *Server) Authorize(t testing.TB, authURL string, claims jwt.MapClaims) (code, state string) {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("授权地址无效: %v", err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("授权地址缺少S256 PKCE校验值: %s", authURL)
+	}
+
+	now := time.Now()
+	merged := jwt.MapClaims{
+		"iss":   s.URL,
+		"aud":   query.Get("client_id"),
+		"sub":   "subject-1",
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = value
+	}
+
+	code = rand.Text()
+	s.mu.Lock()
+	s.codes[code] = grant{challenge: query.Get("code_challenge"), claims: merged}
+	s.mu.Unlock()
+	return code, query.Get("state")
+}
+
+// TokenForm 返回最近一次令牌请求的表单
+func (s *Server) TokenForm() url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokenForm
+}
+
+// SignWith 使用指定密钥签发令牌，kid与身份提供方的公钥相同，用于测试伪造的签名
+func SignWith(t testing.TB, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	return sign(t, key, claims)
+}
+
+func sign(t testing.TB, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	raw, err := signToken(key, claims)
+	if err != nil {
+		t.Fatalf("签发ID令牌失败: %v", err)
+	}
+	return raw
+}
+
+func signToken(key *rsa.PrivateKey, claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = KeyID
+	return token.SignedString(key)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.Key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": KeyID,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// token 令牌端点，授权码只能使用一次，code_verifier必须与授权请求中的code_challenge匹配
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	s.mu.Lock()
+	s.tokenForm = r.PostForm
+	g, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	raw, err := signToken(s.Key, g.claims)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"id_token":     raw,
+		"expires_in":   300,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,183 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testClientID = "client-1"
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Server) {
+	t.Helper()
+	idp := oidctest.NewServer(t)
+	provider := NewProvider(Config{
+		Name:         "test",
+		Issuer:       idp.URL,
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	}, idp.Client())
+	return provider, idp
+}
+
+// login 完成一次授权码登录，返回ID令牌的验证结果
+func login(t *testing.T, provider *Provider, idp *oidctest.Server, claims jwt.MapClaims) (*Claims, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, _ := idp.Authorize(t, authURL, claims)
+	token, err := provider.Exchange(ctx, code, "verifier-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	return provider.VerifyIDToken(ctx, token.IDToken, "nonce-1")
+}
+
+func TestLogin(t *testing.T) {
+	provider, idp := newTestProvider(t)
+
+	claims, err := login(t, provider, idp, jwt.MapClaims{"email": "a@example.com", "email_verified": "true"})
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+	if claims.Subject != "subject-1" || claims.Email != "a@example.com" || !bool(claims.EmailVerified) {
+		t.Fatalf("claims = %+v", claims)
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	provider, idp := newTestProvider(t)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, _ := url.Parse(authURL)
+	query := u.Query()
+	if u.Scheme+"://"+u.Host+u.Path != idp.URL+"/authorize" {
+		t.Errorf("endpoint = %s", authURL)
+	}
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        CodeChallenge("verifier-1"),
+		"code_challenge_method": "S256",
+		"scope":                 "openid email profile",
+	}
+	for name, value := range want {
+		if got := query.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestExchangeSendsVerifier(t *testing.T) {
+	provider, idp := newTestProvider(t)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, _ := idp.Authorize(t, authURL, nil)
+
+	// 校验码与授权请求不一致时身份提供方拒绝换取令牌
+	if _, err := provider.Exchange(ctx, code, "other-verifier"); err == nil {
+		t.Fatal("Exchange with wrong verifier succeeded")
+	}
+	if got := idp.TokenForm().Get("code_verifier"); got != "other-verifier" {
+		t.Fatalf("code_verifier = %q", got)
+	}
+
+	code, _ = idp.Authorize(t, authURL, nil)
+	if _, err := provider.Exchange(ctx, code, "verifier-1"); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	form := idp.TokenForm()
+	if form.Get("code_verifier") != "verifier-1" || form.Get("code") != code || form.Get("client_id") != testClientID {
+		t.Fatalf("token form = %v", form)
+	}
+}
+
+func TestVerifyIDTokenRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		want   error
+	}{
+		{"nonce mismatch", jwt.MapClaims{"nonce": "other"}, ErrNonceMismatch},
+		{"missing nonce", jwt.MapClaims{"nonce": nil}, ErrNonceMismatch},
+		{"wrong audience", jwt.MapClaims{"aud": "other-client"}, ErrInvalidIDToken},
+		{"multiple audiences without azp", jwt.MapClaims{"aud": []string{testClientID, "other-client"}}, ErrInvalidIDToken},
+		{"wrong issuer", jwt.MapClaims{"iss": "https://evil.example.com"}, ErrInvalidIDToken},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-2 * time.Minute).Unix()}, ErrInvalidIDToken},
+		{"missing exp", jwt.MapClaims{"exp": nil}, ErrInvalidIDToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, idp := newTestProvider(t)
+			_, err := login(t, provider, idp, tt.claims)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenAllowsAuthorizedParty(t *testing.T) {
+	provider, idp := newTestProvider(t)
+
+	_, err := login(t, provider, idp, jwt.MapClaims{"aud": []string{testClientID, "other-client"}, "azp": testClientID})
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+}
+
+func TestVerifyIDTokenRejectsForgedSignature(t *testing.T) {
+	provider, idp := newTestProvider(t)
+	ctx := context.Background()
+	if _, err := provider.Discover(ctx); err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := oidctest.SignWith(t, key, jwt.MapClaims{
+		"iss":   idp.URL,
+		"aud":   testClientID,
+		"sub":   "subject-1",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": "nonce-1",
+	})
+	if _, err := provider.VerifyIDToken(ctx, raw, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("err = %v, want %v", err, ErrInvalidIDToken)
+	}
+}
+
+func TestDiscoverRejectsIssuerMismatch(t *testing.T) {
+	idp := oidctest.NewServer(t)
+	// 通过localhost访问同一个身份提供方，文档中的issuer是127.0.0.1
+	issuer := strings.Replace(idp.URL, "127.0.0.1", "localhost", 1)
+	provider := NewProvider(Config{Name: "test", Issuer: issuer, ClientID: testClientID}, idp.Client())
+
+	if _, err := provider.Discover(context.Background()); err == nil {
+		t.Fatal("Discover succeeded with mismatched issuer")
+	}
+}
@@ -0,0 +1,295 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 每页数量的默认限制
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Options 列表接口的分页选项
+type Options struct {
+	Sorts           []string // 允许排序的字段，即数据库列名，字段不能为NULL，id始终允许
+	DefaultSort     string   // 默认排序，"-"前缀表示降序，为空时按id升序
+	DefaultPageSize int      // 为0时使用DefaultPageSize
+	MaxPageSize     int      // 为0时使用MaxPageSize
+}
+
+// Request 解析后的分页参数
+// 默认使用游标分页，按排序字段和id定位上一页的最后一条记录，不需要统计总数；指定page时使用偏移分页
+type Request struct {
+	Size   int
+	Sort   string // 排序字段
+	Desc   bool
+	Page   int // 大于0时使用偏移分页
+	cursor *cursor
+	path   string
+	query  url.Values
+}
+
+// Page 分页结果
+type Page struct {
+	PageSize   int    `json:"page_size"`
+	Sort       string `json:"sort"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Page       int    `json:"page,omitempty"` // 偏移分页时的当前页码
+}
+
+// cursor 游标内容，记录排序方式和上一页最后一条记录的排序字段值及id
+type cursor struct {
+	Sort  string          `json:"s"`
+	Desc  bool            `json:"d"`
+	Value json.RawMessage `json:"v,omitempty"`
+	ID    json.RawMessage `json:"id"`
+}
+
+// Parse 从查询参数中解析分页参数，支持page_size、sort、cursor和page，参数无效时返回400错误
+func Parse(ctx *fiber.Ctx, opts Options) (*Request, error) {
+	maxSize := opts.MaxPageSize
+	if maxSize == 0 {
+		maxSize = MaxPageSize
+	}
+	req := &Request{
+		Size:  opts.DefaultPageSize,
+		path:  ctx.Path(),
+		query: url.Values{},
+	}
+	if req.Size == 0 {
+		req.Size = min(DefaultPageSize, maxSize)
+	}
+	ctx.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		req.query.Add(string(key), string(value))
+	})
+
+	if v := ctx.Query("page_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 1 || size > maxSize {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("每页数量必须在1到%d之间", maxSize))
+		}
+		req.Size = size
+	}
+
+	sort := ctx.Query("sort", opts.DefaultSort)
+	if sort == "" {
+		sort = "id"
+	}
+	req.Sort, req.Desc = strings.CutPrefix(sort, "-")
+	if req.Sort != "id" && !slices.Contains(opts.Sorts, req.Sort) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "不支持的排序字段: "+req.Sort)
+	}
+
+	if v := ctx.Query("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "无效的页码")
+		}
+		req.Page = page
+	}
+
+	if v := ctx.Query("cursor"); v != "" {
+		if req.Page > 0 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "cursor和page不能同时使用")
+		}
+		c, err := decodeCursor(v)
+		// 游标只能用于生成它时的排序方式
+		if err != nil || c.Sort != req.Sort || c.Desc != req.Desc {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "无效的分页游标")
+		}
+		req.cursor = c
+	}
+	return req, nil
+}
+
+// Find 按分页参数查询一页记录，db上应已添加筛选条件和选择列的作用域
+// 多查询一条记录用于判断是否还有下一页，不执行COUNT
+func Find[T any](db *gorm.DB, req *Request) ([]T, *Page, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, nil, err
+	}
+	idField := stmt.Schema.LookUpField("id")
+	sortField := stmt.Schema.LookUpField(req.Sort)
+	if idField == nil || sortField == nil {
+		return nil, nil, fmt.Errorf("分页: %s 没有字段 %s", stmt.Schema.Name, req.Sort)
+	}
+
+	// 只查询部分列时补充排序字段和id，用于排序和生成游标，需在调用方的作用域之后执行
+	query := db.Scopes(func(tx *gorm.DB) *gorm.DB {
+		selects := tx.Statement.Selects
+		if len(selects) == 0 {
+			return tx
+		}
+		for _, column := range []string{"id", req.Sort} {
+			if !slices.Contains(selects, column) {
+				selects = append(selects, column)
+			}
+		}
+		return tx.Select(selects)
+	})
+	if req.cursor != nil {
+		where, err := req.cursor.where(idField, sortField)
+		if err != nil {
+			return nil, nil, fiber.NewError(fiber.StatusBadRequest, "无效的分页游标")
+		}
+		query = query.Where(where)
+	}
+	if req.Sort != "id" {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: req.Sort}, Desc: req.Desc})
+	}
+	query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: req.Desc})
+	if req.Page > 0 {
+		query = query.Offset((req.Page - 1) * req.Size)
+	}
+
+	items := []T{}
+	if err := query.Limit(req.Size + 1).Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+
+	page := &Page{
+		PageSize: req.Size,
+		Sort:     req.sortParam(),
+		Page:     req.Page,
+	}
+	if len(items) > req.Size {
+		items = items[:req.Size]
+		page.HasMore = true
+		if req.Page == 0 {
+			next, err := req.nextCursor(db, idField, sortField, &items[len(items)-1])
+			if err != nil {
+				return nil, nil, err
+			}
+			page.NextCursor = next
+		}
+	}
+	return items, page, nil
+}
+
+// SetLinks 设置Link响应头，包含首页、下一页以及偏移分页时的上一页地址
+func SetLinks(ctx *fiber.Ctx, req *Request, page *Page) {
+	links := []string{req.link("first", nil)}
+	switch {
+	case page.NextCursor != "":
+		links = append(links, req.link("next", map[string]string{"cursor": page.NextCursor}))
+	case req.Page > 0 && page.HasMore:
+		links = append(links, req.link("next", map[string]string{"page": strconv.Itoa(req.Page + 1)}))
+	}
+	if req.Page > 1 {
+		links = append(links, req.link("prev", map[string]string{"page": strconv.Itoa(req.Page - 1)}))
+	}
+	ctx.Set(fiber.HeaderLink, strings.Join(links, ", "))
+}
+
+// link 返回替换分页参数后的地址，地址相对于当前请求
+func (r *Request) link(rel string, params map[string]string) string {
+	query := url.Values{}
+	for key, values := range r.query {
+		if key != "cursor" && key != "page" {
+			query[key] = values
+		}
+	}
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	target := r.path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	return fmt.Sprintf(`<%s>; rel="%s"`, target, rel)
+}
+
+// sortParam 返回sort参数的格式
+func (r *Request) sortParam() string {
+	if r.Desc {
+		return "-" + r.Sort
+	}
+	return r.Sort
+}
+
+// nextCursor 根据本页最后一条记录生成下一页的游标
+func (r *Request) nextCursor(db *gorm.DB, idField, sortField *schema.Field, last interface{}) (string, error) {
+	item := reflect.ValueOf(last).Elem()
+	c := cursor{Sort: r.Sort, Desc: r.Desc}
+
+	id, _ := idField.ValueOf(db.Statement.Context, item)
+	var err error
+	if c.ID, err = json.Marshal(id); err != nil {
+		return "", err
+	}
+	if r.Sort != "id" {
+		value, _ := sortField.ValueOf(db.Statement.Context, item)
+		if c.Value, err = json.Marshal(value); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// where 返回排在游标之后的记录的查询条件
+func (c *cursor) where(idField, sortField *schema.Field) (clause.Expression, error) {
+	op := ">"
+	if c.Desc {
+		op = "<"
+	}
+	id, err := decodeValue(idField, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	idColumn := clause.Column{Name: "id"}
+	if c.Sort == "id" {
+		return gorm.Expr("? "+op+" ?", idColumn, id), nil
+	}
+
+	value, err := decodeValue(sortField, c.Value)
+	if err != nil {
+		return nil, err
+	}
+	column := clause.Column{Name: c.Sort}
+	return gorm.Expr("(? "+op+" ? OR (? = ? AND ? "+op+" ?))", column, value, column, value, idColumn, id), nil
+}
+
+// decodeValue 按字段类型解析游标中的值
+func decodeValue(field *schema.Field, data json.RawMessage) (interface{}, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, fmt.Errorf("分页: 游标缺少 %s 的值", field.DBName)
+	}
+	value := reflect.New(field.FieldType)
+	if err := json.Unmarshal(data, value.Interface()); err != nil {
+		return nil, err
+	}
+	return value.Elem().Interface(), nil
+}
+
+// decodeCursor 解析游标字符串
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
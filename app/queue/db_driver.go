@@ -0,0 +1,161 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 乐观锁模式下每次尝试抢占的候选任务数
+const optimisticCandidates = 5
+
+// DBDriver 基于数据库的队列驱动
+// MySQL 8和Postgres使用 SELECT ... FOR UPDATE SKIP LOCKED 取任务，
+// SQLite等不支持行锁的数据库使用乐观更新轮询
+type DBDriver struct {
+	db *gorm.DB
+}
+
+// NewDBDriver 创建数据库队列驱动
+func NewDBDriver(db *gorm.DB) *DBDriver {
+	return &DBDriver{db: db}
+}
+
+// Push 写入一个新任务
+func (d *DBDriver) Push(ctx context.Context, job *model.QueueJob) error {
+	return transaction.DB(ctx, d.db).Create(job).Error
+}
+
+// Reserve 取出一个可执行的任务并标记为执行中
+func (d *DBDriver) Reserve(ctx context.Context, queue string, timeout time.Duration) (*model.QueueJob, error) {
+	if d.db.Dialector.Name() == "sqlite" {
+		return d.reserveOptimistic(ctx, queue, timeout)
+	}
+
+	var job *model.QueueJob
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var candidate model.QueueJob
+		err := d.available(tx, queue, now, timeout).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Order("available_at ASC, id ASC").
+			Take(&candidate).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := d.markReserved(tx, &candidate, now).Error; err != nil {
+			return err
+		}
+		job = &candidate
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// reserveOptimistic 不依赖行锁取任务，通过带状态条件的更新抢占任务
+func (d *DBDriver) reserveOptimistic(ctx context.Context, queue string, timeout time.Duration) (*model.QueueJob, error) {
+	db := d.db.WithContext(ctx)
+	now := time.Now()
+
+	var candidates []model.QueueJob
+	err := d.available(db, queue, now, timeout).
+		Order("available_at ASC, id ASC").
+		Limit(optimisticCandidates).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range candidates {
+		candidate := &candidates[i]
+		result := d.markReserved(db.Where("status = ? AND attempts = ?", candidate.Status, candidate.Attempts), candidate, now)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		// 已被其他工作进程抢占
+		if result.RowsAffected == 0 {
+			continue
+		}
+		return candidate, nil
+	}
+	return nil, nil
+}
+
+// available 构造查询可执行任务的条件：到期的待执行任务或超时未完成的执行中任务
+func (d *DBDriver) available(db *gorm.DB, queue string, now time.Time, timeout time.Duration) *gorm.DB {
+	return db.Model(&model.QueueJob{}).
+		Where("queue = ?", queue).
+		Where(db.Where("status = ? AND available_at <= ?", model.QueueJobPending, now).
+			Or("status = ? AND reserved_at <= ?", model.QueueJobRunning, now.Add(-timeout)))
+}
+
+// markReserved 将任务标记为执行中并增加尝试次数
+// 取出时间截断到毫秒，与数据库保存的精度一致，以便之后按取出时间校验执行权
+func (d *DBDriver) markReserved(db *gorm.DB, job *model.QueueJob, now time.Time) *gorm.DB {
+	now = now.Truncate(time.Millisecond)
+	result := db.Model(job).Updates(map[string]interface{}{
+		"status":      model.QueueJobRunning,
+		"reserved_at": now,
+		"attempts":    gorm.Expr("attempts + 1"),
+	})
+	if result.Error == nil {
+		job.Status = model.QueueJobRunning
+		job.ReservedAt = &now
+		job.Attempts++
+	}
+	return result
+}
+
+// Delete 删除执行成功的任务
+func (d *DBDriver) Delete(ctx context.Context, job *model.QueueJob) error {
+	return owned(d.reserved(ctx, job).Delete(&model.QueueJob{}))
+}
+
+// Release 将执行失败的任务放回队列
+func (d *DBDriver) Release(ctx context.Context, job *model.QueueJob, availableAt time.Time, lastError string) error {
+	return owned(d.reserved(ctx, job).Updates(map[string]interface{}{
+		"status":       model.QueueJobPending,
+		"available_at": availableAt,
+		"reserved_at":  nil,
+		"last_error":   lastError,
+	}))
+}
+
+// Bury 将任务标记为dead
+func (d *DBDriver) Bury(ctx context.Context, job *model.QueueJob, lastError string) error {
+	return owned(d.reserved(ctx, job).Updates(map[string]interface{}{
+		"status":      model.QueueJobDead,
+		"reserved_at": nil,
+		"last_error":  lastError,
+	}))
+}
+
+// reserved 构造匹配调用方仍持有的任务的条件，任务被其他工作进程重新取出后ReservedAt和Attempts都会变化
+func (d *DBDriver) reserved(ctx context.Context, job *model.QueueJob) *gorm.DB {
+	return d.db.WithContext(ctx).Model(&model.QueueJob{}).
+		Where("id = ? AND status = ? AND reserved_at = ? AND attempts = ?", job.ID, model.QueueJobRunning, job.ReservedAt, job.Attempts)
+}
+
+// owned 将没有更新任何行的结果转换为ErrReservationLost
+func owned(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReservationLost
+	}
+	return nil
+}
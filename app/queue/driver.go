@@ -0,0 +1,31 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+)
+
+// ErrReservationLost 任务的执行权已失效
+// 执行超过超时时间后任务会被其他工作进程重新取出，原工作进程不能再删除或放回该任务
+var ErrReservationLost = errors.New("队列任务的执行权已失效")
+
+// Driver 队列存储驱动
+type Driver interface {
+	// Push 写入一个新任务
+	Push(ctx context.Context, job *model.QueueJob) error
+	// Reserve 从指定队列取出一个可执行的任务并标记为执行中，没有任务时返回nil
+	// 执行中的任务超过timeout未完成时视为工作进程已退出，可被重新取出
+	Reserve(ctx context.Context, queue string, timeout time.Duration) (*model.QueueJob, error)
+	// Delete、Release和Bury只修改调用方仍持有的任务，即执行中且ReservedAt和Attempts与Reserve返回时一致，
+	// 任务已被重新取出时返回ErrReservationLost
+
+	// Delete 删除执行成功的任务
+	Delete(ctx context.Context, job *model.QueueJob) error
+	// Release 将执行失败的任务放回队列，在availableAt之后重试
+	Release(ctx context.Context, job *model.QueueJob, availableAt time.Time, lastError string) error
+	// Bury 将任务标记为dead，不再重试
+	Bury(ctx context.Context, job *model.QueueJob, lastError string) error
+}
@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+)
+
+// MemoryDriver 基于内存的队列驱动，用于测试和本地开发
+// 任务不会持久化，进程退出后丢失
+type MemoryDriver struct {
+	mu     sync.Mutex
+	nextID uint64
+	jobs   map[uint64]*model.QueueJob
+}
+
+// NewMemoryDriver 创建内存队列驱动
+func NewMemoryDriver() *MemoryDriver {
+	return &MemoryDriver{
+		jobs: make(map[uint64]*model.QueueJob),
+	}
+}
+
+// Push 写入一个新任务，在事务中调用时等事务提交后才写入
+func (d *MemoryDriver) Push(ctx context.Context, job *model.QueueJob) error {
+	transaction.AfterCommit(ctx, func(context.Context) {
+		d.push(job)
+	})
+	return nil
+}
+
+// push 保存任务并分配ID
+func (d *MemoryDriver) push(job *model.QueueJob) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.nextID++
+	now := time.Now()
+	job.ID = d.nextID
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	stored := *job
+	d.jobs[job.ID] = &stored
+}
+
+// Reserve 取出一个可执行的任务并标记为执行中
+func (d *MemoryDriver) Reserve(ctx context.Context, queue string, timeout time.Duration) (*model.QueueJob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	var candidates []*model.QueueJob
+	for _, job := range d.jobs {
+		if job.Queue != queue {
+			continue
+		}
+		pending := job.Status == model.QueueJobPending && !job.AvailableAt.After(now)
+		expired := job.Status == model.QueueJobRunning && job.ReservedAt != nil && !job.ReservedAt.After(now.Add(-timeout))
+		if pending || expired {
+			candidates = append(candidates, job)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].AvailableAt.Equal(candidates[j].AvailableAt) {
+			return candidates[i].ID < candidates[j].ID
+		}
+		return candidates[i].AvailableAt.Before(candidates[j].AvailableAt)
+	})
+
+	job := candidates[0]
+	job.Status = model.QueueJobRunning
+	job.ReservedAt = &now
+	job.Attempts++
+	job.UpdatedAt = now
+
+	reserved := *job
+	return &reserved, nil
+}
+
+// Delete 删除执行成功的任务
+func (d *MemoryDriver) Delete(ctx context.Context, job *model.QueueJob) error {
+	return d.update(job, func(stored *model.QueueJob) {
+		delete(d.jobs, stored.ID)
+	})
+}
+
+// Release 将执行失败的任务放回队列
+func (d *MemoryDriver) Release(ctx context.Context, job *model.QueueJob, availableAt time.Time, lastError string) error {
+	return d.update(job, func(stored *model.QueueJob) {
+		stored.Status = model.QueueJobPending
+		stored.AvailableAt = availableAt
+		stored.ReservedAt = nil
+		stored.LastError = lastError
+	})
+}
+
+// Bury 将任务标记为dead
+func (d *MemoryDriver) Bury(ctx context.Context, job *model.QueueJob, lastError string) error {
+	return d.update(job, func(stored *model.QueueJob) {
+		stored.Status = model.QueueJobDead
+		stored.ReservedAt = nil
+		stored.LastError = lastError
+	})
+}
+
+// Jobs 返回当前所有任务的副本，用于测试断言
+func (d *MemoryDriver) Jobs() []model.QueueJob {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	jobs := make([]model.QueueJob, 0, len(d.jobs))
+	for _, job := range d.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// update 在锁内修改调用方仍持有的任务，任务已被重新取出时返回ErrReservationLost
+func (d *MemoryDriver) update(job *model.QueueJob, fn func(stored *model.QueueJob)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stored, ok := d.jobs[job.ID]
+	if !ok || stored.Status != model.QueueJobRunning || stored.Attempts != job.Attempts ||
+		stored.ReservedAt == nil || job.ReservedAt == nil || !stored.ReservedAt.Equal(*job.ReservedAt) {
+		return ErrReservationLost
+	}
+	fn(stored)
+	stored.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,308 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+)
+
+// 默认队列名称
+const DefaultQueue = "default"
+
+// HandlerFunc 队列任务处理函数
+type HandlerFunc func(ctx context.Context, job *model.QueueJob) error
+
+// Options 队列执行选项
+type Options struct {
+	Workers      int           // 工作协程数量
+	Queues       []string      // 监听的队列名称，按顺序优先处理
+	PollInterval time.Duration // 队列为空时的轮询间隔
+	MaxAttempts  int           // 默认最大尝试次数
+	Backoff      time.Duration // 首次重试前的等待时间，之后按指数增长
+	MaxBackoff   time.Duration // 重试等待时间的上限
+	Timeout      time.Duration // 单个任务的执行超时时间，超时未完成的任务会被重新取出
+}
+
+// Queue 后台任务队列，管理任务处理器和工作协程池
+type Queue struct {
+	driver   Driver
+	options  Options
+	handlers map[string]HandlerFunc
+	mu       sync.RWMutex
+
+	ctx     context.Context // 任务执行使用的上下文，在关闭超时后取消
+	cancel  context.CancelFunc
+	stop    chan struct{} // 关闭后工作协程不再取新任务
+	wg      sync.WaitGroup
+	started bool
+}
+
+// New 创建任务队列
+func New(driver Driver, options Options) *Queue {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if len(options.Queues) == 0 {
+		options.Queues = []string{DefaultQueue}
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = time.Second
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 1
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = 10 * time.Second
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 5 * time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		driver:   driver,
+		options:  options,
+		handlers: make(map[string]HandlerFunc),
+		ctx:      ctx,
+		cancel:   cancel,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Register 注册任务处理器
+func (q *Queue) Register(jobType string, handler HandlerFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+// Handle 注册类型化的任务处理器，任务载荷会被解码为T
+func Handle[T any](q *Queue, jobType string, handler func(ctx context.Context, payload T) error) {
+	q.Register(jobType, func(ctx context.Context, job *model.QueueJob) error {
+		var payload T
+		if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+			return Permanent(fmt.Errorf("解码任务载荷失败: %w", err))
+		}
+		return handler(ctx, payload)
+	})
+}
+
+// DispatchOption 投递任务的选项
+type DispatchOption func(job *model.QueueJob)
+
+// Delay 延迟指定时间后执行
+func Delay(d time.Duration) DispatchOption {
+	return func(job *model.QueueJob) {
+		job.AvailableAt = time.Now().Add(d)
+	}
+}
+
+// OnQueue 投递到指定队列
+func OnQueue(name string) DispatchOption {
+	return func(job *model.QueueJob) {
+		job.Queue = name
+	}
+}
+
+// MaxAttempts 设置最大尝试次数
+func MaxAttempts(n int) DispatchOption {
+	return func(job *model.QueueJob) {
+		if n > 0 {
+			job.MaxAttempts = n
+		}
+	}
+}
+
+// Dispatch 投递任务，payload会被编码为JSON
+func (q *Queue) Dispatch(ctx context.Context, jobType string, payload interface{}, opts ...DispatchOption) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("编码任务载荷失败: %w", err)
+	}
+
+	job := &model.QueueJob{
+		Queue:       DefaultQueue,
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      model.QueueJobPending,
+		MaxAttempts: q.options.MaxAttempts,
+		AvailableAt: time.Now(),
+	}
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	return q.driver.Push(ctx, job)
+}
+
+// Start 启动工作协程
+func (q *Queue) Start() {
+	if q.started {
+		return
+	}
+	q.started = true
+
+	for i := 0; i < q.options.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	log.Printf("任务队列已启动: %d 个工作协程，队列 %v", q.options.Workers, q.options.Queues)
+}
+
+// Shutdown 停止取新任务并等待执行中的任务完成
+// ctx到期时取消仍在执行的任务，被取消的任务会重新入队
+func (q *Queue) Shutdown(ctx context.Context) error {
+	select {
+	case <-q.stop:
+		return nil
+	default:
+		close(q.stop)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		log.Println("任务队列已停止")
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// work 工作协程主循环
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		if q.processNext() {
+			continue
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-time.After(q.options.PollInterval):
+		}
+	}
+}
+
+// processNext 按队列优先级取出并执行一个任务，没有任务时返回false
+func (q *Queue) processNext() bool {
+	for _, name := range q.options.Queues {
+		job, err := q.driver.Reserve(q.ctx, name, q.options.Timeout)
+		if err != nil {
+			log.Printf("队列 %s 取任务失败: %v", name, err)
+			return false
+		}
+		if job != nil {
+			q.process(job)
+			return true
+		}
+	}
+	return false
+}
+
+// process 执行任务并根据结果删除、重试或标记为dead
+func (q *Queue) process(job *model.QueueJob) {
+	err := q.execute(job)
+
+	// 使用独立的上下文写回结果，避免关闭时因上下文取消而丢失状态
+	ctx := context.Background()
+	switch {
+	case err == nil:
+		err = q.driver.Delete(ctx, job)
+	case isPermanent(err) || job.Attempts >= job.MaxAttempts:
+		log.Printf("队列任务 %s#%d 执行失败，不再重试: %v", job.Type, job.ID, err)
+		err = q.driver.Bury(ctx, job, err.Error())
+	default:
+		backoff := q.backoff(job.Attempts)
+		log.Printf("队列任务 %s#%d 第%d次执行失败，%v 后重试: %v", job.Type, job.ID, job.Attempts, backoff, err)
+		err = q.driver.Release(ctx, job, time.Now().Add(backoff), err.Error())
+	}
+	if errors.Is(err, ErrReservationLost) {
+		log.Printf("队列任务 %s#%d 执行超时后已被重新取出，忽略本次执行结果", job.Type, job.ID)
+	} else if err != nil {
+		log.Printf("队列任务 %s#%d 更新状态失败: %v", job.Type, job.ID, err)
+	}
+}
+
+// execute 调用任务处理器，应用超时并将panic转换为错误
+func (q *Queue) execute(job *model.QueueJob) (err error) {
+	q.mu.RLock()
+	handler, ok := q.handlers[job.Type]
+	q.mu.RUnlock()
+	if !ok {
+		return Permanent(fmt.Errorf("未注册的任务类型: %s", job.Type))
+	}
+
+	ctx, cancel := context.WithTimeout(q.ctx, q.options.Timeout)
+	defer cancel()
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v\n%s", p, debug.Stack())
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// backoff 计算第attempts次失败后的重试等待时间
+func (q *Queue) backoff(attempts int) time.Duration {
+	backoff := q.options.Backoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if q.options.MaxBackoff > 0 && backoff >= q.options.MaxBackoff {
+			return q.options.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// permanentError 不应重试的错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent 包装不应重试的错误，任务会直接标记为dead
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// isPermanent 判断是否为不应重试的错误
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
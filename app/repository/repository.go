@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/NextEraAbyss/fiber-template/app/pagination"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound 记录不存在
+var ErrNotFound = errors.New("记录不存在")
+
+// Scope 查询作用域，用于添加筛选条件、选择列等
+type Scope = func(*gorm.DB) *gorm.DB
+
+// Queries 模型的通用数据访问方法，专用仓库接口嵌入它并声明返回自身类型的WithTx
+type Queries[T any] interface {
+	// Find 查询满足作用域条件的全部记录
+	Find(ctx context.Context, scopes ...Scope) ([]T, error)
+	// FindByID 通过主键查询记录，不存在时返回ErrNotFound
+	FindByID(ctx context.Context, id interface{}) (*T, error)
+	// Create 创建记录
+	Create(ctx context.Context, entity *T) error
+	// Update 更新记录的指定列，不执行模型的更新钩子
+	Update(ctx context.Context, entity *T, values map[string]interface{}) error
+	// Delete 删除记录，模型支持软删除时为软删除
+	Delete(ctx context.Context, entity *T) error
+	// Paginate 按分页参数查询一页记录
+	Paginate(ctx context.Context, page *pagination.Request, scopes ...Scope) ([]T, *pagination.Page, error)
+}
+
+// Repository 模型的通用数据访问接口
+type Repository[T any] interface {
+	Queries[T]
+	// WithTx 返回在指定事务中执行的仓库
+	WithTx(tx *gorm.DB) Repository[T]
+}
+
+// GormRepository 基于GORM的Repository实现，可嵌入到模型的专用仓库中
+type GormRepository[T any] struct {
+	db    *gorm.DB
+	bound bool // 通过WithTx绑定了事务，不再使用上下文中的事务
+}
+
+// NewGormRepository 创建基于GORM的仓库
+func NewGormRepository[T any](db *gorm.DB) *GormRepository[T] {
+	return &GormRepository[T]{db: db}
+}
+
+// DB 返回绑定上下文的数据库连接，上下文中有事务时使用该事务，供专用仓库编写自定义查询
+func (r *GormRepository[T]) DB(ctx context.Context) *gorm.DB {
+	if r.bound {
+		return r.db.WithContext(ctx)
+	}
+	return transaction.DB(ctx, r.db)
+}
+
+// Find 查询满足作用域条件的全部记录
+func (r *GormRepository[T]) Find(ctx context.Context, scopes ...Scope) ([]T, error) {
+	items := []T{}
+	err := r.DB(ctx).Scopes(scopes...).Find(&items).Error
+	return items, err
+}
+
+// FindByID 通过主键查询记录
+func (r *GormRepository[T]) FindByID(ctx context.Context, id interface{}) (*T, error) {
+	return r.First(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Where("id = ?", id)
+	})
+}
+
+// First 查询满足作用域条件的第一条记录，不存在时返回ErrNotFound
+func (r *GormRepository[T]) First(ctx context.Context, scopes ...Scope) (*T, error) {
+	var item T
+	if err := r.DB(ctx).Scopes(scopes...).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Create 创建记录
+func (r *GormRepository[T]) Create(ctx context.Context, entity *T) error {
+	return r.DB(ctx).Create(entity).Error
+}
+
+// Update 更新记录的指定列
+func (r *GormRepository[T]) Update(ctx context.Context, entity *T, values map[string]interface{}) error {
+	return r.DB(ctx).Model(entity).UpdateColumns(values).Error
+}
+
+// Delete 删除记录
+func (r *GormRepository[T]) Delete(ctx context.Context, entity *T) error {
+	return r.DB(ctx).Delete(entity).Error
+}
+
+// Paginate 按分页参数查询一页记录
+func (r *GormRepository[T]) Paginate(ctx context.Context, page *pagination.Request, scopes ...Scope) ([]T, *pagination.Page, error) {
+	return pagination.Find[T](r.DB(ctx).Model(new(T)).Scopes(scopes...), page)
+}
+
+// WithTx 返回在指定事务中执行的仓库
+func (r *GormRepository[T]) WithTx(tx *gorm.DB) Repository[T] {
+	return r.Bind(tx)
+}
+
+// Bind 返回绑定到指定事务的GormRepository，供专用仓库实现返回自身类型的WithTx
+func (r *GormRepository[T]) Bind(tx *gorm.DB) *GormRepository[T] {
+	return &GormRepository[T]{db: tx, bound: true}
+}
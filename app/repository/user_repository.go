@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserRepository 用户数据访问接口
+type UserRepository interface {
+	Queries[model.User]
+	// WithTx 返回在指定事务中执行的用户仓库
+	WithTx(tx *gorm.DB) UserRepository
+	// FindByLogin 通过用户名或邮箱查询用户
+	FindByLogin(ctx context.Context, login string) (*model.User, error)
+	// FindByEmail 通过邮箱查询用户
+	FindByEmail(ctx context.Context, email string) (*model.User, error)
+	// FindForUpdate 查询用户并加行锁，需在Transaction中调用
+	FindForUpdate(ctx context.Context, id uint) (*model.User, error)
+	// FindLockedOut 查询在指定时间处于锁定期或有登录失败记录的用户，锁定截止时间晚的在前
+	FindLockedOut(ctx context.Context, now time.Time) ([]model.User, error)
+	// Transaction 在事务中执行fn，fn应使用传入的上下文调用仓库方法，已在事务中时使用保存点
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// userRepository 基于GORM的UserRepository实现
+type userRepository struct {
+	*GormRepository[model.User]
+}
+
+// NewUserRepository 创建基于GORM的用户仓库
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{GormRepository: NewGormRepository[model.User](db)}
+}
+
+// WithTx 返回在指定事务中执行的用户仓库，保留用户仓库的专用方法
+func (r *userRepository) WithTx(tx *gorm.DB) UserRepository {
+	return &userRepository{GormRepository: r.GormRepository.Bind(tx)}
+}
+
+// FindByLogin 通过用户名或邮箱查询用户，邮箱不区分大小写
+func (r *userRepository) FindByLogin(ctx context.Context, login string) (*model.User, error) {
+	return r.First(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Where("username = ? OR email = ?", login, strings.ToLower(login))
+	})
+}
+
+// FindByEmail 通过邮箱查询用户，邮箱不区分大小写
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.First(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Where("email = ?", strings.ToLower(email))
+	})
+}
+
+// FindForUpdate 查询用户并加行锁
+func (r *userRepository) FindForUpdate(ctx context.Context, id uint) (*model.User, error) {
+	return r.First(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id)
+	})
+}
+
+// FindLockedOut 查询处于锁定期或有登录失败记录的用户
+func (r *userRepository) FindLockedOut(ctx context.Context, now time.Time) ([]model.User, error) {
+	return r.Find(ctx, func(db *gorm.DB) *gorm.DB {
+		return db.Where("locked_until > ? OR failed_login_count > 0", now).Order("locked_until DESC")
+	})
+}
+
+// Transaction 在事务中执行fn
+func (r *userRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return transaction.Run(ctx, r.db, fn)
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestUserRepositoryWithTx(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.User{}); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+
+	users := NewUserRepository(db)
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	// 绑定事务的仓库仍是UserRepository，专用方法在同一事务中执行
+	err = db.Transaction(func(tx *gorm.DB) error {
+		txUsers := users.WithTx(tx)
+		user := &model.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+		if err := txUsers.Create(ctx, user); err != nil {
+			return err
+		}
+		if _, err := txUsers.FindByLogin(ctx, "alice"); err != nil {
+			t.Errorf("FindByLogin: %v", err)
+		}
+		if _, err := txUsers.FindByEmail(ctx, "Alice@Example.com"); err != nil {
+			t.Errorf("FindByEmail: %v", err)
+		}
+		if _, err := txUsers.FindForUpdate(ctx, user.ID); err != nil {
+			t.Errorf("FindForUpdate: %v", err)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("err = %v, want %v", err, errRollback)
+	}
+
+	if _, err := users.FindByLogin(ctx, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("after rollback: err = %v, want %v", err, ErrNotFound)
+	}
+}
@@ -0,0 +1,20 @@
+package resource
+
+import (
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/serializer"
+)
+
+// File 文件的输出定义，存储驱动和存储路径不会输出
+var File = &serializer.Serializer[model.File]{
+	Fields: []serializer.Field[model.File]{
+		{Name: "id", Columns: []string{"id"}, Value: func(f *model.File) interface{} { return f.ID }},
+		{Name: "user_id", Columns: []string{"user_id"}, Value: func(f *model.File) interface{} { return f.UserID }},
+		{Name: "name", Columns: []string{"name"}, Value: func(f *model.File) interface{} { return f.Name }},
+		{Name: "mime_type", Columns: []string{"mime_type"}, Value: func(f *model.File) interface{} { return f.MimeType }},
+		{Name: "size", Columns: []string{"size"}, Value: func(f *model.File) interface{} { return f.Size }},
+		{Name: "checksum", Columns: []string{"checksum"}, Value: func(f *model.File) interface{} { return f.Checksum }},
+		{Name: "created_at", Columns: []string{"created_at"}, Value: func(f *model.File) interface{} { return f.CreatedAt }},
+		{Name: "updated_at", Columns: []string{"updated_at"}, Value: func(f *model.File) interface{} { return f.UpdatedAt }},
+	},
+}
@@ -0,0 +1,80 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/serializer"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// identity 用户关联的第三方登录身份
+type identity struct {
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// User 用户的输出定义，密码、令牌版本、登录锁定和两步验证密钥等字段不能被查询和输出
+// 邮箱验证、两步验证状态和第三方登录身份是私有数据，只对本人和管理员输出，见OwnerOf
+var User = &serializer.Serializer[model.User]{
+	Fields: []serializer.Field[model.User]{
+		{Name: "id", Columns: []string{"id"}, Value: func(u *model.User) interface{} { return u.ID }},
+		{Name: "username", Columns: []string{"username"}, Value: func(u *model.User) interface{} { return u.Username }},
+		{Name: "email", Columns: []string{"email"}, Value: func(u *model.User) interface{} { return u.Email }},
+		{Name: "avatar", Columns: []string{"avatar"}, Value: func(u *model.User) interface{} { return u.Avatar }},
+		{Name: "role", Columns: []string{"role"}, Value: func(u *model.User) interface{} { return u.Role }},
+		{Name: "is_active", Columns: []string{"is_active"}, Value: func(u *model.User) interface{} { return u.IsActive }},
+		{Name: "locale", Columns: []string{"locale"}, Value: func(u *model.User) interface{} { return u.Locale }},
+		{Name: "email_verified_at", Columns: []string{"email_verified_at"}, Private: true, Value: func(u *model.User) interface{} { return u.EmailVerifiedAt }},
+		{Name: "two_factor_enabled", Columns: []string{"two_factor_enabled_at"}, Private: true, Value: func(u *model.User) interface{} { return u.IsTwoFactorEnabled() }},
+		{Name: "created_at", Columns: []string{"created_at"}, Value: func(u *model.User) interface{} { return u.CreatedAt }},
+		{Name: "updated_at", Columns: []string{"updated_at"}, Value: func(u *model.User) interface{} { return u.UpdatedAt }},
+	},
+	Includes: []serializer.Include[model.User]{
+		{Name: "identities", Private: true, Load: loadIdentities},
+	},
+}
+
+// OwnerOf 返回判断私有字段是否对viewer可见的函数，用于Selection.Owner
+// viewer为nil表示匿名访问，只有用户本人和管理员可以查看私有字段
+func OwnerOf(viewer *model.User) func(*model.User) bool {
+	return func(u *model.User) bool {
+		return viewer != nil && (viewer.Role == model.RoleAdmin || viewer.ID == u.ID)
+	}
+}
+
+// loadIdentities 加载用户关联的第三方登录身份
+func loadIdentities(ctx *fiber.Ctx, users []*model.User) (func(*model.User) (interface{}, bool), error) {
+	ids := make([]uint, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+
+	var rows []model.UserIdentity
+	err := config.GetDB().WithContext(ctx.UserContext()).
+		Where("user_id IN ?", ids).
+		Order("id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	identities := make(map[uint][]identity, len(ids))
+	for _, row := range rows {
+		identities[row.UserID] = append(identities[row.UserID], identity{
+			Provider:    row.Provider,
+			Email:       row.Email,
+			LastLoginAt: row.LastLoginAt,
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+
+	return func(u *model.User) (interface{}, bool) {
+		if identities[u.ID] == nil {
+			return []identity{}, true
+		}
+		return identities[u.ID], true
+	}, nil
+}
@@ -1,8 +1,12 @@
 package router
 
 import (
+	"time"
+
 	"github.com/NextEraAbyss/fiber-template/app/controller"
 	"github.com/NextEraAbyss/fiber-template/app/middleware"
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/config"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -28,9 +32,94 @@ func SetupRoutes(app *fiber.App) {
 	v1 := api.Group("/v1")
 
 	// 初始化控制器
+	authController := controller.NewAuthController()
+	twoFactorController := controller.NewTwoFactorController()
+	apiKeyController := controller.NewAPIKeyController()
+	oidcController := controller.NewOIDCController()
+	fileController := controller.NewFileController()
 	userController := controller.NewUserController()
+	jobController := controller.NewJobController()
+	statisticsController := controller.NewStatisticsController()
+
+	// 要求已验证邮箱，用于上传文件、创建API密钥等可能被滥用的操作
+	verified := middleware.RequireVerified()
+
+	// 认证路由
+	auth := v1.Group("/auth")
+	auth.Post("/register", authController.Register)                                     // 用户注册
+	auth.Post("/login", authController.Login)                                           // 用户登录
+	auth.Post("/password/forgot", authController.ForgotPassword)                        // 发送重置密码邮件
+	auth.Post("/password/reset", authController.ResetPassword)                          // 重置密码
+	auth.Post("/email/verify", authController.VerifyEmail)                              // 验证邮箱
+	auth.Post("/email/resend", middleware.JWTAuth(), authController.ResendVerification) // 重新发送验证邮件
+	auth.Put("/email", middleware.JWTAuth(), authController.ChangeEmail)                // 修改邮箱
+
+	// 第三方登录路由
+	auth.Get("/oidc", oidcController.GetProviders)                // 获取第三方登录方式
+	auth.Get("/oidc/:provider", oidcController.Redirect)          // 跳转到身份提供方登录
+	auth.Get("/oidc/:provider/callback", oidcController.Callback) // 身份提供方登录回调
+
+	// 两步验证路由
+	twoFactor := auth.Group("/2fa")
+	twoFactor.Post("/verify", twoFactorController.Verify)                                                // 两步验证登录
+	twoFactor.Post("/setup", middleware.JWTAuth(), twoFactorController.Setup)                            // 获取两步验证密钥
+	twoFactor.Post("/confirm", middleware.JWTAuth(), twoFactorController.Confirm)                        // 确认启用两步验证
+	twoFactor.Post("/recovery-codes", middleware.JWTAuth(), twoFactorController.RegenerateRecoveryCodes) // 重新生成恢复码
+	twoFactor.Post("/disable", middleware.JWTAuth(), twoFactorController.Disable)                        // 关闭两步验证
+
+	// API密钥路由，只能使用JWT管理，避免泄露的API密钥创建新密钥
+	apiKeys := v1.Group("/api-keys", middleware.JWTAuth())
+	apiKeys.Get("/", apiKeyController.GetAPIKeys)              // 获取API密钥列表
+	apiKeys.Post("/", verified, apiKeyController.CreateAPIKey) // 创建API密钥
+	apiKeys.Put("/:id", apiKeyController.UpdateAPIKey)         // 修改API密钥
+	apiKeys.Delete("/:id", apiKeyController.DeleteAPIKey)      // 吊销API密钥
+
+	// 文件路由
+	files := v1.Group("/files", middleware.Authenticate())
+	files.Get("/", fileController.GetFiles)                                      // 获取文件列表
+	files.Post("/", verified, fileController.Upload)                             // 上传文件
+	files.Get("/uploads", fileController.GetUploads)                             // 获取未完成的分片上传
+	files.Post("/uploads", verified, fileController.CreateUpload)                // 创建分片上传
+	files.Get("/uploads/:id", fileController.GetUpload)                          // 查询分片上传进度
+	files.Patch("/uploads/:id", verified, fileController.UploadChunk)            // 上传分片
+	files.Post("/uploads/:id/complete", verified, fileController.CompleteUpload) // 完成分片上传
+	files.Delete("/uploads/:id", fileController.AbortUpload)                     // 取消分片上传
+	files.Get("/:id", fileController.GetFile)                                    // 获取文件信息
+	files.Get("/:id/download", fileController.Download)                          // 下载文件
+	files.Post("/:id/url", verified, fileController.CreateDownloadURL)           // 创建签名下载链接
+	files.Delete("/:id", fileController.DeleteFile)                              // 删除文件
+
+	// 签名下载链接，通过签名校验权限，不需要认证
+	v1.Get("/downloads/:id", fileController.SignedDownload) // 通过签名链接下载文件
 
 	// 用户路由
-	v1.Get("/users", userController.GetUsers)    // 获取用户列表
-	v1.Get("/users/:id", userController.GetUser) // 获取单个用户
+	// 列表和详情是公开接口，可选认证后本人和管理员能看到私有字段，缓存按用户区分
+	usersCache := middleware.ResponseCache(middleware.CacheConfig{TTL: time.Minute, Tags: []string{"users"}})
+	v1.Get("/users", middleware.OptionalAuth(), usersCache, userController.GetUsers)               // 获取用户列表
+	v1.Get("/users/:id", middleware.OptionalAuth(), usersCache, userController.GetUser)            // 获取单个用户
+	v1.Post("/users/:id/avatar", middleware.Authenticate(), verified, userController.UploadAvatar) // 上传用户头像
+	v1.Delete("/users/:id/avatar", middleware.Authenticate(), userController.DeleteAvatar)         // 删除用户头像
+	v1.Get("/avatars/:id/:name", userController.GetAvatar)                                         // 获取头像图片
+
+	// 统计路由
+	stats := v1.Group("/stats", middleware.Authenticate(), middleware.RequireRole(model.RoleAdmin))
+	stats.Get("/users", statisticsController.GetUserStatistics) // 获取每日用户统计
+
+	// 管理员路由
+	admin := v1.Group("/admin", middleware.Authenticate(), middleware.RequireRole(model.RoleAdmin))
+	admin.Get("/jobs", jobController.GetJobs)                   // 获取定时任务列表
+	admin.Get("/jobs/:name/runs", jobController.GetJobRuns)     // 获取定时任务执行记录
+	admin.Post("/jobs/:name/trigger", jobController.TriggerJob) // 立即执行定时任务
+	admin.Post("/jobs/:name/pause", jobController.PauseJob)     // 暂停定时任务
+	admin.Post("/jobs/:name/resume", jobController.ResumeJob)   // 恢复定时任务
+	admin.Get("/users/locked", userController.GetLockedUsers)   // 获取登录锁定的用户
+	admin.Post("/users/:id/unlock", userController.UnlockUser)  // 解除用户登录锁定
+
+	// 开发调试路由，仅在调试模式下注册
+	if config.Load().App.Debug {
+		mailController := controller.NewMailController()
+		dev := v1.Group("/dev")
+		dev.Get("/mail", mailController.GetTemplates)          // 获取邮件模板列表
+		dev.Get("/mail/:name", mailController.PreviewTemplate) // 预览邮件模板
+	}
 }
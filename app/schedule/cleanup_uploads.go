@@ -0,0 +1,124 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"gorm.io/gorm"
+)
+
+// uploadTempPrefix 普通上传的临时文件前缀，请求结束时会被删除，残留的文件来自异常退出的进程
+const uploadTempPrefix = "upload-"
+
+// CleanupUploads 清理过期的分片上传会话、一次性下载链接的使用记录和上传临时目录中残留的文件
+// 临时文件保存在各节点本地，因此不声明为单例任务，每个节点清理自己的临时目录
+type CleanupUploads struct {
+	*Runner
+	db       *gorm.DB
+	tempPath string
+	ttl      time.Duration
+}
+
+// Schedule 返回定时任务的执行时间，使用cron表达式
+func (t *CleanupUploads) Schedule() string {
+	// 每小时执行一次
+	return "0 0 * * * *"
+}
+
+// Task 定时任务的执行逻辑
+// 先删除过期的会话，再删除没有对应会话的分片文件，其他节点删除的会话对应的文件也会被清理
+func (t *CleanupUploads) Task(ctx context.Context) error {
+	result := t.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&model.UploadSession{})
+	if result.Error != nil {
+		return result.Error
+	}
+	nonces := t.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&model.DownloadNonce{})
+	if nonces.Error != nil {
+		return nonces.Error
+	}
+
+	entries, err := os.ReadDir(t.tempPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	sessionFiles := make(map[string]string)
+	var removed int
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if id, ok := strings.CutPrefix(name, model.UploadSessionFilePrefix); ok {
+			sessionFiles[id] = name
+			continue
+		}
+		if strings.HasPrefix(name, uploadTempPrefix) {
+			info, err := entry.Info()
+			if err == nil && time.Since(info.ModTime()) > t.ttl && t.remove(name) {
+				removed++
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(sessionFiles))
+	for id := range sessionFiles {
+		ids = append(ids, id)
+	}
+	for len(ids) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch := ids[:min(len(ids), 500)]
+		ids = ids[len(batch):]
+
+		var existing []string
+		err := t.db.WithContext(ctx).Model(&model.UploadSession{}).Where("id IN ?", batch).Pluck("id", &existing).Error
+		if err != nil {
+			return err
+		}
+		keep := make(map[string]bool, len(existing))
+		for _, id := range existing {
+			keep[id] = true
+		}
+		for _, id := range batch {
+			if !keep[id] && t.remove(sessionFiles[id]) {
+				removed++
+			}
+		}
+	}
+
+	log.Printf("上传临时文件已清理: 过期会话 %d 个, 下载链接使用记录 %d 条, 删除文件 %d 个", result.RowsAffected, nonces.RowsAffected, removed)
+	return nil
+}
+
+// remove 删除临时目录中的文件
+func (t *CleanupUploads) remove(name string) bool {
+	if err := os.Remove(filepath.Join(t.tempPath, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("删除上传临时文件失败: %s: %v", name, err)
+		return false
+	}
+	return true
+}
+
+// NewCleanupUploads 创建并返回一个新的CleanupUploads实例
+// ttl为分片上传会话的有效期，超过该时间未修改的普通上传临时文件也会被删除
+func NewCleanupUploads(db *gorm.DB, tempPath string, ttl time.Duration) *CleanupUploads {
+	t := &CleanupUploads{db: db, tempPath: tempPath, ttl: ttl}
+	t.Runner = NewRunner("cleanup_uploads", t.Schedule(), t.Task, Options{
+		Overlap:      OverlapSkip,
+		Timeout:      10 * time.Minute,
+		MaxRetries:   1,
+		RetryBackoff: time.Minute,
+	})
+	return t
+}
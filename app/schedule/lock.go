@@ -0,0 +1,114 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 定义错误
+var (
+	ErrLockHeld = errors.New("锁已被其他节点持有")
+	ErrLockLost = errors.New("锁已失效")
+)
+
+// Locker 分布式锁接口
+// 用于保证单例任务在多个节点中同一时间只有一个节点执行
+type Locker interface {
+	// Acquire 获取锁，锁被其他节点持有时返回ErrLockHeld
+	Acquire(ctx context.Context, key string, ttl time.Duration) (Lease, error)
+}
+
+// Lease 已获取的锁租约
+type Lease interface {
+	// Refresh 续约，租约已被其他节点抢占时返回ErrLockLost
+	Refresh(ctx context.Context) error
+	// Release 释放锁
+	Release(ctx context.Context) error
+}
+
+// DBLocker 基于数据库行锁的分布式锁实现，支持MySQL和Postgres
+type DBLocker struct {
+	db    *gorm.DB
+	owner string
+}
+
+// NewDBLocker 创建数据库分布式锁，owner为当前节点标识
+func NewDBLocker(db *gorm.DB, owner string) *DBLocker {
+	return &DBLocker{
+		db:    db,
+		owner: owner,
+	}
+}
+
+// Acquire 获取锁
+// 在事务中对锁记录加行锁，仅当锁不存在、已过期或由当前节点持有时才能获取
+func (l *DBLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lease, error) {
+	err := l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var lock model.ScheduleLock
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", key).Take(&lock).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = tx.Create(&model.ScheduleLock{
+				Name:      key,
+				Owner:     l.owner,
+				ExpiresAt: now.Add(ttl),
+			}).Error
+			// 其他节点并发插入了同一把锁
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrLockHeld
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if lock.Owner != l.owner && lock.ExpiresAt.After(now) {
+			return ErrLockHeld
+		}
+
+		return tx.Model(&lock).Updates(map[string]interface{}{
+			"owner":      l.owner,
+			"expires_at": now.Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dbLease{locker: l, key: key, ttl: ttl}, nil
+}
+
+// dbLease 数据库锁租约
+type dbLease struct {
+	locker *DBLocker
+	key    string
+	ttl    time.Duration
+}
+
+// Refresh 续约
+func (l *dbLease) Refresh(ctx context.Context) error {
+	result := l.locker.db.WithContext(ctx).Model(&model.ScheduleLock{}).
+		Where("name = ? AND owner = ?", l.key, l.locker.owner).
+		Update("expires_at", time.Now().Add(l.ttl))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release 释放锁
+func (l *dbLease) Release(ctx context.Context) error {
+	return l.locker.db.WithContext(ctx).
+		Where("name = ? AND owner = ?", l.key, l.locker.owner).
+		Delete(&model.ScheduleLock{}).Error
+}
@@ -0,0 +1,78 @@
+package schedule
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"gorm.io/gorm"
+)
+
+// 每批删除的执行记录数量，避免长时间锁表
+const pruneJobRunsBatch = 1000
+
+// PruneJobRuns 删除超过保留期的定时任务执行记录，防止job_runs表无限增长
+type PruneJobRuns struct {
+	*Runner
+	db        *gorm.DB
+	retention time.Duration
+}
+
+// Schedule 返回定时任务的执行时间，使用cron表达式
+func (t *PruneJobRuns) Schedule() string {
+	// 每天凌晨3点执行
+	return "0 0 3 * * *"
+}
+
+// Task 定时任务的执行逻辑
+// 按开始时间删除，长时间处于running状态的记录来自异常退出的节点，也会一并删除
+func (t *PruneJobRuns) Task(ctx context.Context) error {
+	cutoff := time.Now().Add(-t.retention)
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var ids []uint
+		err := t.db.WithContext(ctx).Model(&model.JobRun{}).
+			Where("started_at < ?", cutoff).
+			Order("id").
+			Limit(pruneJobRunsBatch).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		result := t.db.WithContext(ctx).Where("id IN ?", ids).Delete(&model.JobRun{})
+		if result.Error != nil {
+			return result.Error
+		}
+		total += result.RowsAffected
+		if len(ids) < pruneJobRunsBatch {
+			break
+		}
+	}
+
+	log.Printf("定时任务执行记录已清理: %d 条", total)
+	return nil
+}
+
+// NewPruneJobRuns 创建并返回一个新的PruneJobRuns实例，retention为执行记录的保留时长
+func NewPruneJobRuns(db *gorm.DB, retention time.Duration) *PruneJobRuns {
+	t := &PruneJobRuns{db: db, retention: retention}
+	t.Runner = NewRunner("prune_job_runs", t.Schedule(), t.Task, Options{
+		Overlap:      OverlapSkip,
+		Timeout:      10 * time.Minute,
+		MaxRetries:   1,
+		RetryBackoff: time.Minute,
+		Singleton:    true,
+		LockTTL:      time.Minute,
+	})
+	return t
+}
@@ -0,0 +1,385 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/robfig/cron/v3"
+)
+
+// TaskFunc 定时任务的执行函数，需要响应ctx的取消
+type TaskFunc func(ctx context.Context) error
+
+// OverlapPolicy 上一次执行尚未结束时的处理策略
+type OverlapPolicy int
+
+const (
+	// OverlapSkip 跳过本次执行（默认）
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue 排队等待上一次执行结束后再执行
+	OverlapQueue
+	// OverlapAllow 允许多次执行并发进行
+	OverlapAllow
+)
+
+// 默认的重试等待时间和锁租约时长
+const (
+	defaultRetryBackoff = time.Second
+	defaultLockTTL      = 30 * time.Second
+)
+
+// 定义错误
+var (
+	ErrNotStarted = errors.New("定时任务尚未启动")
+	ErrJobRunning = errors.New("定时任务正在执行")
+)
+
+// 当前节点标识、分布式锁和持久化存储，由SetNodeID、SetLocker和SetStore配置
+var (
+	nodeID string
+	locker Locker
+	store  Store
+)
+
+// SetNodeID 设置当前节点标识
+func SetNodeID(id string) {
+	nodeID = id
+}
+
+// SetLocker 设置分布式锁实现，为nil时单例任务会在每个节点执行
+func SetLocker(l Locker) {
+	locker = l
+}
+
+// SetStore 设置持久化存储，为nil时不记录执行历史，暂停状态仅在本节点生效
+func SetStore(s Store) {
+	store = s
+}
+
+// Options 定时任务的执行选项
+type Options struct {
+	Overlap      OverlapPolicy // 重叠执行策略
+	Timeout      time.Duration // 单次执行的超时时间，0表示不限制
+	MaxRetries   int           // 执行失败后的最大重试次数
+	RetryBackoff time.Duration // 首次重试前的等待时间，之后按指数增长
+	MaxBackoff   time.Duration // 重试等待时间的上限，0表示不限制
+	Singleton    bool          // 是否为单例任务，单例任务同一时间只在一个节点执行
+	LockTTL      time.Duration // 单例任务的锁租约时长，执行期间会定期续约
+}
+
+// Runner 按cron表达式调度并执行单个定时任务
+// 任务通过嵌入*Runner获得Start、Stop、Trigger、Pause等方法
+type Runner struct {
+	name    string
+	spec    string
+	task    TaskFunc
+	options Options
+
+	cron    *cron.Cron
+	entryID cron.EntryID
+	ctx     context.Context
+	cancel  context.CancelFunc
+	mu      sync.Mutex     // 用于防止任务重叠执行
+	wg      sync.WaitGroup // 等待所有执行中的任务退出
+	paused  atomic.Bool    // 未配置存储时使用的本地暂停状态
+
+	lease   Lease // 单例任务最近一次获取的锁租约
+	leaseMu sync.Mutex
+}
+
+// NewRunner 创建定时任务执行器
+func NewRunner(name, spec string, task TaskFunc, options Options) *Runner {
+	if options.MaxRetries > 0 && options.RetryBackoff <= 0 {
+		options.RetryBackoff = defaultRetryBackoff
+	}
+	if options.LockTTL <= 0 {
+		options.LockTTL = defaultLockTTL
+	}
+
+	return &Runner{
+		name:    name,
+		spec:    spec,
+		task:    task,
+		options: options,
+	}
+}
+
+// Name 返回任务名称
+func (r *Runner) Name() string {
+	return r.name
+}
+
+// Options 返回任务的执行选项
+func (r *Runner) Options() Options {
+	return r.options
+}
+
+// NextRun 返回下一次计划执行的时间，任务未启动时返回零值
+func (r *Runner) NextRun() time.Time {
+	if r.cron == nil {
+		return time.Time{}
+	}
+	return r.cron.Entry(r.entryID).Next
+}
+
+// Start 启动定时任务
+func (r *Runner) Start() {
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.cron = cron.New(cron.WithSeconds())
+	entryID, err := r.cron.AddFunc(r.spec, r.run)
+	if err != nil {
+		log.Printf("启动定时任务失败: %s: %v", r.name, err)
+		return
+	}
+	r.entryID = entryID
+	r.cron.Start()
+	log.Printf("定时任务已启动: %s [%s]", r.name, r.spec)
+}
+
+// Stop 停止定时任务，取消正在执行的任务并等待其退出
+func (r *Runner) Stop() {
+	if r.cron == nil {
+		return
+	}
+	r.cancel()
+	<-r.cron.Stop().Done()
+	r.wg.Wait()
+
+	// 释放持有的锁，使其他节点可以立即接管
+	r.leaseMu.Lock()
+	if r.lease != nil {
+		if err := r.lease.Release(context.Background()); err != nil {
+			log.Printf("定时任务 %s 释放分布式锁失败: %v", r.name, err)
+		}
+		r.lease = nil
+	}
+	r.leaseMu.Unlock()
+
+	log.Printf("定时任务已停止: %s", r.name)
+}
+
+// Pause 暂停任务，暂停期间计划执行会被跳过，但仍可手动触发
+func (r *Runner) Pause(ctx context.Context) error {
+	return r.setPaused(ctx, true)
+}
+
+// Resume 恢复已暂停的任务
+func (r *Runner) Resume(ctx context.Context) error {
+	return r.setPaused(ctx, false)
+}
+
+// Paused 返回任务是否已暂停
+func (r *Runner) Paused(ctx context.Context) (bool, error) {
+	if store != nil {
+		return store.IsPaused(ctx, r.name)
+	}
+	return r.paused.Load(), nil
+}
+
+// setPaused 设置暂停状态，配置了存储时写入存储以便所有节点生效
+func (r *Runner) setPaused(ctx context.Context, paused bool) error {
+	if store != nil {
+		if err := store.SetPaused(ctx, r.name, paused); err != nil {
+			return err
+		}
+	}
+	r.paused.Store(paused)
+	return nil
+}
+
+// Trigger 立即在后台执行一次任务
+// 任务正在执行或锁被其他节点持有时返回错误
+func (r *Runner) Trigger() error {
+	if r.ctx == nil {
+		return ErrNotStarted
+	}
+
+	started := make(chan error, 1)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.runOnce(model.JobTriggerManual, started)
+	}()
+	return <-started
+}
+
+// run 由cron按计划调用
+func (r *Runner) run() {
+	paused, err := r.Paused(r.ctx)
+	if err != nil {
+		log.Printf("定时任务 %s 读取暂停状态失败: %v", r.name, err)
+	}
+	if paused {
+		return
+	}
+
+	r.wg.Add(1)
+	defer r.wg.Done()
+	r.runOnce(model.JobTriggerSchedule, nil)
+}
+
+// runOnce 按重叠策略和分布式锁执行一次任务
+// started不为nil时，会在任务开始执行或放弃执行时收到通知
+func (r *Runner) runOnce(trigger string, started chan<- error) {
+	notify := func(err error) {
+		if started != nil {
+			started <- err
+			started = nil
+		}
+	}
+
+	switch r.options.Overlap {
+	case OverlapSkip:
+		if !r.mu.TryLock() {
+			log.Printf("定时任务 %s 上一次执行尚未结束，跳过本次执行", r.name)
+			notify(ErrJobRunning)
+			return
+		}
+		defer r.mu.Unlock()
+	case OverlapQueue:
+		// 排队的任务视为已触发，不阻塞调用方
+		notify(nil)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+	}
+
+	ctx := r.ctx
+	if r.options.Singleton && locker != nil {
+		lease, err := locker.Acquire(ctx, "schedule:"+r.name, r.options.LockTTL)
+		if errors.Is(err, ErrLockHeld) {
+			notify(err)
+			return
+		}
+		if err != nil {
+			log.Printf("定时任务 %s 获取分布式锁失败: %v", r.name, err)
+			notify(err)
+			return
+		}
+
+		// 执行结束后不立即释放锁，而是保留租约直到过期，
+		// 避免其他节点因时钟偏差在同一周期内再次执行
+		r.leaseMu.Lock()
+		r.lease = lease
+		r.leaseMu.Unlock()
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		done := make(chan struct{})
+		go r.heartbeat(ctx, lease, cancel, done)
+		defer func() {
+			close(done)
+			cancel()
+		}()
+	}
+	notify(nil)
+
+	run := &model.JobRun{
+		Job:       r.name,
+		Trigger:   trigger,
+		Status:    model.JobRunRunning,
+		Node:      nodeID,
+		StartedAt: time.Now(),
+	}
+	r.beginRun(run)
+
+	err := r.execute(ctx)
+	if err != nil {
+		log.Printf("定时任务执行失败: %s: %v", r.name, err)
+	}
+	r.finishRun(run, err)
+}
+
+// beginRun 记录任务开始执行
+func (r *Runner) beginRun(run *model.JobRun) {
+	if store == nil {
+		return
+	}
+	if err := store.BeginRun(context.Background(), run); err != nil {
+		log.Printf("定时任务 %s 写入执行记录失败: %v", r.name, err)
+	}
+}
+
+// finishRun 记录任务执行结果
+func (r *Runner) finishRun(run *model.JobRun, err error) {
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.DurationMs = finishedAt.Sub(run.StartedAt).Milliseconds()
+	run.Status = model.JobRunSuccess
+	if err != nil {
+		run.Status = model.JobRunFailed
+		run.Error = err.Error()
+	}
+
+	if store == nil || run.ID == 0 {
+		return
+	}
+	if err := store.FinishRun(context.Background(), run); err != nil {
+		log.Printf("定时任务 %s 更新执行记录失败: %v", r.name, err)
+	}
+}
+
+// heartbeat 在任务执行期间定期续约，续约失败时取消任务
+func (r *Runner) heartbeat(ctx context.Context, lease Lease, cancel context.CancelFunc, done <-chan struct{}) {
+	ticker := time.NewTicker(r.options.LockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := lease.Refresh(ctx); err != nil {
+				log.Printf("定时任务 %s 续约分布式锁失败，取消执行: %v", r.name, err)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// execute 执行任务，失败时按指数退避重试
+func (r *Runner) execute(ctx context.Context) error {
+	backoff := r.options.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := r.attempt(ctx)
+		if err == nil || attempt >= r.options.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		log.Printf("定时任务 %s 第%d次执行失败，%v 后重试: %v", r.name, attempt+1, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+
+		backoff *= 2
+		if r.options.MaxBackoff > 0 && backoff > r.options.MaxBackoff {
+			backoff = r.options.MaxBackoff
+		}
+	}
+}
+
+// attempt 执行一次任务，应用超时并将panic转换为错误
+func (r *Runner) attempt(ctx context.Context) (err error) {
+	if r.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.options.Timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v\n%s", p, debug.Stack())
+		}
+	}()
+
+	return r.task(ctx)
+}
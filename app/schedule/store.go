@@ -0,0 +1,69 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Store 定时任务状态和执行记录的持久化接口
+// 暂停状态保存在共享存储中，以便在所有节点生效
+type Store interface {
+	// IsPaused 返回任务是否已暂停
+	IsPaused(ctx context.Context, name string) (bool, error)
+	// SetPaused 设置任务的暂停状态
+	SetPaused(ctx context.Context, name string, paused bool) error
+	// BeginRun 记录一次任务执行的开始
+	BeginRun(ctx context.Context, run *model.JobRun) error
+	// FinishRun 记录一次任务执行的结束
+	FinishRun(ctx context.Context, run *model.JobRun) error
+}
+
+// DBStore 基于数据库的定时任务存储
+type DBStore struct {
+	db *gorm.DB
+}
+
+// NewDBStore 创建数据库定时任务存储
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+// IsPaused 返回任务是否已暂停
+func (s *DBStore) IsPaused(ctx context.Context, name string) (bool, error) {
+	var job model.ScheduleJob
+	err := s.db.WithContext(ctx).Where("name = ?", name).Take(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return job.Paused, nil
+}
+
+// SetPaused 设置任务的暂停状态
+func (s *DBStore) SetPaused(ctx context.Context, name string, paused bool) error {
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"paused", "updated_at"}),
+	}).Create(&model.ScheduleJob{Name: name, Paused: paused}).Error
+}
+
+// BeginRun 记录一次任务执行的开始
+func (s *DBStore) BeginRun(ctx context.Context, run *model.JobRun) error {
+	return s.db.WithContext(ctx).Create(run).Error
+}
+
+// FinishRun 记录一次任务执行的结束
+func (s *DBStore) FinishRun(ctx context.Context, run *model.JobRun) error {
+	return s.db.WithContext(ctx).Model(run).Updates(map[string]interface{}{
+		"status":      run.Status,
+		"error":       run.Error,
+		"finished_at": run.FinishedAt,
+		"duration_ms": run.DurationMs,
+	}).Error
+}
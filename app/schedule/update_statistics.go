@@ -1,52 +1,170 @@
 package schedule
 
 import (
+	"context"
+	"errors"
 	"log"
+	"time"
 
-	"github.com/robfig/cron/v3"
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// UpdateStatistics 更新统计数据的定时任务（示例）
-// 实现ScheduleTask接口
+// 最多回填的天数，避免首次运行时扫描过长的历史
+const statisticsBackfillDays = 366
+
+// UpdateStatistics 汇总每日用户统计数据的定时任务
+// 实现ScheduleTask接口，也是编写定时任务的参考实现：
+//   - 通过构造函数注入依赖，不直接读取全局配置
+//   - 所有数据库操作使用传入的ctx，以便超时和停止时能及时退出
+//   - 按日期幂等写入，重复执行或多次重试不会产生重复数据
+//   - 声明为单例任务，多副本部署时只在一个节点执行
 type UpdateStatistics struct {
-	cron *cron.Cron
+	*Runner
+	db *gorm.DB
 }
 
 // Schedule 返回定时任务的执行时间，使用cron表达式
 func (t *UpdateStatistics) Schedule() string {
-	// 每30秒执行一次（示例）
-	return "*/30 * * * * *"
+	// 每10分钟执行一次，保持当天数据的实时性
+	return "0 */10 * * * *"
 }
 
 // Task 定时任务的执行逻辑
-func (t *UpdateStatistics) Task() {
-	log.Println("执行示例定时任务")
+// 重新计算当天和前一天的数据，并回填缺失的日期
+func (t *UpdateStatistics) Task(ctx context.Context) error {
+	days, err := t.pendingDays(ctx, today())
+	if err != nil {
+		return err
+	}
+
+	for _, day := range days {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := t.updateDay(ctx, day); err != nil {
+			return err
+		}
+	}
 
-	// 这里添加您的业务逻辑
-	log.Println("示例任务执行完成")
+	log.Printf("用户统计已更新: %d 天", len(days))
+	return nil
 }
 
-// Start 启动定时任务
-func (t *UpdateStatistics) Start() {
-	t.cron = cron.New(cron.WithSeconds())
-	_, err := t.cron.AddFunc(t.Schedule(), t.Task)
+// pendingDays 返回需要计算的日期
+// 包括当天、前一天（当天结束前的数据可能不完整）以及回填范围内缺失的日期
+func (t *UpdateStatistics) pendingDays(ctx context.Context, today time.Time) ([]time.Time, error) {
+	var first model.User
+	err := t.db.WithContext(ctx).Unscoped().Order("created_at ASC").Take(&first).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []time.Time{today}, nil
+	}
 	if err != nil {
-		log.Printf("启动定时任务失败: %v", err)
-		return
+		return nil, err
+	}
+
+	start := startOfDay(first.CreatedAt)
+	if earliest := today.AddDate(0, 0, -statisticsBackfillDays); start.Before(earliest) {
+		start = earliest
+	}
+
+	var recorded []time.Time
+	err = t.db.WithContext(ctx).Model(&model.UserStatistic{}).
+		Where("date >= ?", start).
+		Pluck("date", &recorded).Error
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make(map[string]bool, len(recorded))
+	for _, date := range recorded {
+		exists[date.Format(time.DateOnly)] = true
+	}
+
+	yesterday := today.AddDate(0, 0, -1)
+	var days []time.Time
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		if !exists[day.Format(time.DateOnly)] || !day.Before(yesterday) {
+			days = append(days, day)
+		}
 	}
-	t.cron.Start()
-	log.Printf("定时任务已启动: [%s]", t.Schedule())
+	return days, nil
 }
 
-// Stop 停止定时任务
-func (t *UpdateStatistics) Stop() {
-	if t.cron != nil {
-		t.cron.Stop()
-		log.Println("定时任务已停止")
+// updateDay 计算并写入指定日期的统计数据
+// 总数按当天结束时的用户计算；激活状态只记录了当前值，因此历史日期的激活数为近似值
+func (t *UpdateStatistics) updateDay(ctx context.Context, day time.Time) error {
+	end := day.AddDate(0, 0, 1)
+
+	stat := model.UserStatistic{Date: day}
+	err := t.db.WithContext(ctx).Unscoped().Model(&model.User{}).
+		Where("created_at >= ? AND created_at < ?", day, end).
+		Count(&stat.NewUsers).Error
+	if err != nil {
+		return err
+	}
+
+	var rows []struct {
+		Role     string
+		IsActive int
+		Total    int64
+	}
+	err = t.db.WithContext(ctx).Unscoped().Model(&model.User{}).
+		Select("role, is_active, COUNT(*) AS total").
+		Where("created_at < ?", end).
+		Where("deleted_at IS NULL OR deleted_at >= ?", end).
+		Group("role, is_active").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		stat.TotalUsers += row.Total
+		if row.IsActive == model.UserActive {
+			stat.ActiveUsers += row.Total
+		} else {
+			stat.InactiveUsers += row.Total
+		}
+
+		switch row.Role {
+		case model.RoleAdmin:
+			stat.AdminUsers += row.Total
+		case model.RoleUser:
+			stat.RegularUsers += row.Total
+		case model.RoleGuest:
+			stat.GuestUsers += row.Total
+		}
 	}
+
+	return t.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"new_users", "total_users", "active_users", "inactive_users", "admin_users", "regular_users", "guest_users", "updated_at"}),
+	}).Create(&stat).Error
+}
+
+// today 返回当天零点
+func today() time.Time {
+	return startOfDay(time.Now())
+}
+
+// startOfDay 返回指定时间所在日期的零点
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.In(time.Local).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
 }
 
 // NewUpdateStatistics 创建并返回一个新的UpdateStatistics实例
-func NewUpdateStatistics() *UpdateStatistics {
-	return &UpdateStatistics{}
+func NewUpdateStatistics(db *gorm.DB) *UpdateStatistics {
+	t := &UpdateStatistics{db: db}
+	t.Runner = NewRunner("update_statistics", t.Schedule(), t.Task, Options{
+		Overlap:      OverlapSkip,
+		Timeout:      5 * time.Minute,
+		MaxRetries:   2,
+		RetryBackoff: 10 * time.Second,
+		Singleton:    true,
+		LockTTL:      time.Minute,
+	})
+	return t
 }
@@ -0,0 +1,191 @@
+package serializer
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Field 可输出的字段
+type Field[T any] struct {
+	Name    string   // 输出的字段名，也是fields参数中使用的名称
+	Columns []string // 输出该字段需要查询的列
+	Private bool     // 只对Selection.Owner判定可见的记录输出
+	Value   func(*T) interface{}
+}
+
+// Include 可通过include参数加载的关联数据
+type Include[T any] struct {
+	Name    string   // include参数中使用的名称，也是输出的字段名
+	Columns []string // 加载关联需要查询的列，id始终会被查询
+	Private bool     // 只对Selection.Owner判定可见的记录加载和输出
+	// Load 批量加载一组记录的关联数据，返回的函数给出每条记录的关联数据，ok为false时不输出该字段
+	Load func(ctx *fiber.Ctx, items []*T) (func(*T) (value interface{}, ok bool), error)
+}
+
+// Serializer 模型的输出定义，只有声明过的字段可以被查询和输出
+type Serializer[T any] struct {
+	Fields   []Field[T]
+	Includes []Include[T]
+}
+
+// Selection 一次请求选择的字段和关联
+type Selection[T any] struct {
+	fields   []*Field[T]
+	includes []*Include[T]
+	owner    func(*T) bool
+}
+
+// Parse 解析fields和include查询参数，均为逗号分隔的名称列表，未指定fields时输出全部字段
+// 名称无效时返回400错误，私有字段和关联默认不输出，需通过Owner指定可见的记录
+func (s *Serializer[T]) Parse(ctx *fiber.Ctx) (*Selection[T], error) {
+	sel := s.All()
+	sel.owner = nil
+	if v := ctx.Query("fields"); v != "" {
+		sel.fields = nil
+		for _, name := range splitList(v) {
+			i := slices.IndexFunc(s.Fields, func(f Field[T]) bool { return f.Name == name })
+			if i < 0 {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "不支持的字段: "+name)
+			}
+			if !slices.Contains(sel.fields, &s.Fields[i]) {
+				sel.fields = append(sel.fields, &s.Fields[i])
+			}
+		}
+	}
+	for _, name := range splitList(ctx.Query("include")) {
+		i := slices.IndexFunc(s.Includes, func(inc Include[T]) bool { return inc.Name == name })
+		if i < 0 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "不支持的关联: "+name)
+		}
+		if !slices.Contains(sel.includes, &s.Includes[i]) {
+			sel.includes = append(sel.includes, &s.Includes[i])
+		}
+	}
+	return sel, nil
+}
+
+// All 返回包含全部字段、不加载关联的选择，私有字段也会输出
+func (s *Serializer[T]) All() *Selection[T] {
+	sel := &Selection[T]{owner: func(*T) bool { return true }}
+	for i := range s.Fields {
+		sel.fields = append(sel.fields, &s.Fields[i])
+	}
+	return sel
+}
+
+// Serialize 输出一条记录的全部字段
+func (s *Serializer[T]) Serialize(item *T) map[string]interface{} {
+	return s.All().serialize(item)
+}
+
+// SerializeMany 输出一组记录的全部字段
+func (s *Serializer[T]) SerializeMany(items []T) []map[string]interface{} {
+	sel := s.All()
+	out := make([]map[string]interface{}, len(items))
+	for i := range items {
+		out[i] = sel.serialize(&items[i])
+	}
+	return out
+}
+
+// Owner 设置私有字段和关联对哪些记录可见，通常是当前用户本人的记录或管理员可见的全部记录
+func (sel *Selection[T]) Owner(fn func(*T) bool) *Selection[T] {
+	sel.owner = fn
+	return sel
+}
+
+// Scope 返回只查询所选字段需要的列的GORM作用域，用于db.Scopes
+func (sel *Selection[T]) Scope(db *gorm.DB) *gorm.DB {
+	columns := []string{"id"}
+	add := func(cols []string) {
+		for _, col := range cols {
+			if !slices.Contains(columns, col) {
+				columns = append(columns, col)
+			}
+		}
+	}
+	for _, f := range sel.fields {
+		add(f.Columns)
+	}
+	for _, inc := range sel.includes {
+		add(inc.Columns)
+	}
+	return db.Select(columns)
+}
+
+// One 输出一条记录
+func (sel *Selection[T]) One(ctx *fiber.Ctx, item *T) (map[string]interface{}, error) {
+	out, err := sel.Many(ctx, []T{*item})
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+// Many 输出一组记录，关联数据按组批量加载
+func (sel *Selection[T]) Many(ctx *fiber.Ctx, items []T) ([]map[string]interface{}, error) {
+	ptrs := make([]*T, len(items))
+	out := make([]map[string]interface{}, len(items))
+	for i := range items {
+		ptrs[i] = &items[i]
+		out[i] = sel.serialize(ptrs[i])
+	}
+
+	for _, inc := range sel.includes {
+		// 私有关联只为可见的记录加载
+		var visible []*T
+		var index []int
+		for i, item := range ptrs {
+			if !inc.Private || sel.visible(item) {
+				visible = append(visible, item)
+				index = append(index, i)
+			}
+		}
+		if len(visible) == 0 {
+			continue
+		}
+
+		value, err := inc.Load(ctx, visible)
+		if err != nil {
+			return nil, err
+		}
+		for j, item := range visible {
+			if v, ok := value(item); ok {
+				out[index[j]][inc.Name] = v
+			}
+		}
+	}
+	return out, nil
+}
+
+// serialize 输出一条记录的所选字段
+func (sel *Selection[T]) serialize(item *T) map[string]interface{} {
+	out := make(map[string]interface{}, len(sel.fields)+len(sel.includes))
+	visible := sel.visible(item)
+	for _, f := range sel.fields {
+		if f.Private && !visible {
+			continue
+		}
+		out[f.Name] = f.Value(item)
+	}
+	return out
+}
+
+// visible 判断私有字段和关联对该记录是否可见
+func (sel *Selection[T]) visible(item *T) bool {
+	return sel.owner != nil && sel.owner(item)
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// API密钥格式为 ftk_<8位十六进制标识>_<随机密钥>，前12个字符作为Prefix保存
+const (
+	apiKeyPrefix    = "ftk_"
+	apiKeyPrefixLen = len(apiKeyPrefix) + 8
+)
+
+// apiKeyTouchInterval 最近使用时间的更新间隔，避免每个请求都写数据库
+const apiKeyTouchInterval = time.Minute
+
+// 定义错误
+var (
+	ErrAPIKeyNotFound = errors.New("API密钥不存在")
+	ErrInvalidAPIKey  = errors.New("API密钥无效或已过期")
+	ErrAPIKeyLimit    = errors.New("API密钥数量已达上限")
+	ErrInvalidExpiry  = errors.New("过期时间必须晚于当前时间")
+)
+
+// CreateAPIKeyParams 创建API密钥参数
+type CreateAPIKeyParams struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=read write"`
+	ExpiresAt *time.Time `json:"expires_at"` // 为空表示永不过期
+}
+
+// UpdateAPIKeyParams 修改API密钥参数，密钥本身和过期时间不能修改
+type UpdateAPIKeyParams struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=read write"`
+}
+
+// APIKeyService API密钥服务
+type APIKeyService struct {
+	config       *config.Config
+	db           *gorm.DB
+	userService  *UserService
+	auditService *AuditService
+}
+
+// NewAPIKeyService 创建新的API密钥服务实例
+func NewAPIKeyService() *APIKeyService {
+	return &APIKeyService{
+		config:       config.Load(),
+		db:           config.GetDB(),
+		userService:  DefaultUserService(),
+		auditService: NewAuditService(),
+	}
+}
+
+// List 获取用户的所有API密钥
+func (s *APIKeyService) List(ctx context.Context, userID uint) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+// Create 为用户创建API密钥，返回的明文密钥只有这一次可以获取
+func (s *APIKeyService) Create(ctx context.Context, user *model.User, params CreateAPIKeyParams, client ClientInfo) (*model.APIKey, string, error) {
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(time.Now()) {
+		return nil, "", ErrInvalidExpiry
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&model.APIKey{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+		return nil, "", err
+	}
+	if count >= int64(s.config.Security.APIKeyMaxPerUser) {
+		return nil, "", ErrAPIKeyLimit
+	}
+
+	plain, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key := &model.APIKey{
+		UserID:    user.ID,
+		Name:      params.Name,
+		Prefix:    plain[:apiKeyPrefixLen],
+		KeyHash:   hashToken(plain),
+		Scopes:    uniqueScopes(params.Scopes),
+		ExpiresAt: params.ExpiresAt,
+	}
+	if err := s.db.WithContext(ctx).Create(key).Error; err != nil {
+		return nil, "", err
+	}
+
+	s.auditService.Record(ctx, AuditEntry{
+		Action:  model.AuditAPIKeyCreated,
+		UserID:  user.ID,
+		Client:  client,
+		Details: map[string]interface{}{"key_id": key.ID, "prefix": key.Prefix},
+	})
+	return key, plain, nil
+}
+
+// Update 修改API密钥的名称和权限
+func (s *APIKeyService) Update(ctx context.Context, userID uint, id uint64, params UpdateAPIKeyParams) (*model.APIKey, error) {
+	key, err := s.find(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Name = params.Name
+	key.Scopes = uniqueScopes(params.Scopes)
+	if err := s.db.WithContext(ctx).Select("name", "scopes", "updated_at").Save(key).Error; err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Delete 吊销API密钥，之后使用该密钥的请求会立即被拒绝
+func (s *APIKeyService) Delete(ctx context.Context, userID uint, id uint64, client ClientInfo) error {
+	key, err := s.find(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Delete(key).Error; err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, AuditEntry{
+		Action:  model.AuditAPIKeyRevoked,
+		UserID:  userID,
+		Client:  client,
+		Details: map[string]interface{}{"key_id": key.ID, "prefix": key.Prefix},
+	})
+	return nil
+}
+
+// Authenticate 验证API密钥并返回密钥所属的用户
+func (s *APIKeyService) Authenticate(ctx context.Context, plain string) (*model.User, *model.APIKey, error) {
+	if len(plain) <= apiKeyPrefixLen+1 || !strings.HasPrefix(plain, apiKeyPrefix) || plain[apiKeyPrefixLen] != '_' {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	var key model.APIKey
+	err := s.db.WithContext(ctx).Where("prefix = ?", plain[:apiKeyPrefixLen]).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashToken(plain))) != 1 || key.IsExpired() {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	// 启用状态和角色必须读取最新值，不使用用户缓存
+	user, err := s.userService.FindUserByID(ctx, key.UserID)
+	if err != nil {
+		if err == ErrUserNotFound {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+	if user.IsActive != model.UserActive {
+		return nil, nil, ErrUserDisabled
+	}
+
+	s.touch(ctx, &key)
+	return user, &key, nil
+}
+
+// touch 更新密钥的最近使用时间，更新失败不影响请求
+func (s *APIKeyService) touch(ctx context.Context, key *model.APIKey) {
+	now := time.Now()
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < apiKeyTouchInterval {
+		return
+	}
+	key.LastUsedAt = &now
+	if err := s.db.WithContext(ctx).Model(key).UpdateColumn("last_used_at", now).Error; err != nil {
+		log.Printf("更新API密钥使用时间失败: %s: %v", key.Prefix, err)
+	}
+}
+
+// find 获取属于指定用户的API密钥
+func (s *APIKeyService) find(ctx context.Context, userID uint, id uint64) (*model.APIKey, error) {
+	var key model.APIKey
+	err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// generateAPIKey 生成新的明文API密钥
+func generateAPIKey() (string, error) {
+	id := make([]byte, 4)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(id) + "_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// uniqueScopes 去除重复的权限
+func uniqueScopes(scopes []string) model.APIKeyScopes {
+	result := make(model.APIKeyScopes, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result
+}
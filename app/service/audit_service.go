@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// ClientInfo 发起请求的客户端信息
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// AuditEntry 审计事件
+type AuditEntry struct {
+	Action  string
+	UserID  uint // 事件涉及的用户，为0表示未知用户
+	ActorID uint // 执行操作的用户，为0时与UserID相同
+	Client  ClientInfo
+	Details map[string]interface{}
+}
+
+// AuditRecorder 写入审计日志的接口，AuditService为默认实现
+type AuditRecorder interface {
+	Record(ctx context.Context, entry AuditEntry)
+}
+
+// AuditService 安全审计服务
+type AuditService struct {
+	db *gorm.DB
+}
+
+// NewAuditService 创建新的安全审计服务实例
+func NewAuditService() *AuditService {
+	return &AuditService{
+		db: config.GetDB(),
+	}
+}
+
+// Record 写入审计日志，写入失败只记录日志，不影响业务流程
+// 在事务中调用时随事务提交或回滚
+func (s *AuditService) Record(ctx context.Context, entry AuditEntry) {
+	record := model.AuditLog{
+		Action:    entry.Action,
+		IP:        entry.Client.IP,
+		UserAgent: truncate(entry.Client.UserAgent, 255),
+	}
+	if entry.UserID != 0 {
+		record.UserID = &entry.UserID
+	}
+	if entry.ActorID != 0 {
+		record.ActorID = &entry.ActorID
+	} else {
+		record.ActorID = record.UserID
+	}
+	if len(entry.Details) > 0 {
+		if data, err := json.Marshal(entry.Details); err == nil {
+			record.Details = string(data)
+		}
+	}
+
+	if err := transaction.DB(ctx, s.db).Create(&record).Error; err != nil {
+		log.Printf("写入审计日志失败: %s: %v", entry.Action, err)
+	}
+}
+
+// truncate 按字符截断字符串
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
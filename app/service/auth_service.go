@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NextEraAbyss/fiber-template/app/cache"
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// 定义错误
+var (
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	ErrUserDisabled       = errors.New("用户已被禁用")
+	ErrTokenRevoked       = errors.New("令牌已失效")
+	ErrUserExists         = errors.New("用户名或邮箱已被注册")
+	ErrInvalidUsername    = errors.New("用户名格式不正确")
+	ErrInvalidChallenge   = errors.New("两步验证已过期，请重新登录")
+	ErrInvalidTokenType   = errors.New("无效的令牌类型")
+)
+
+// AccountLockedError 账号因连续登录失败被临时锁定
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccountLockedError) Error() string {
+	return "登录失败次数过多，账号已被临时锁定"
+}
+
+// 两步验证中间令牌的用途
+const twoFactorPurpose = "2fa"
+
+// LoginResult 登录结果，启用两步验证时Token为空，ChallengeToken为换取正式令牌的中间令牌
+type LoginResult struct {
+	Token          string
+	ChallengeToken string
+	User           *model.User
+}
+
+// TwoFactorLoginParams 两步验证登录参数
+type TwoFactorLoginParams struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required,max=20"`
+}
+
+// LoginParams 登录参数
+type LoginParams struct {
+	Login    string `json:"login" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+// RegisterParams 注册参数
+type RegisterParams struct {
+	Username string `json:"username" validate:"required,min=3,max=100"`
+	Email    string `json:"email" validate:"required,email,max=100"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+// AuthService 认证服务
+type AuthService struct {
+	config              *config.Config
+	db                  *gorm.DB
+	cache               cache.Store
+	userService         *UserService
+	verificationService *VerificationService
+	twoFactorService    *TwoFactorService
+	auditService        *AuditService
+}
+
+// NewAuthService 创建新的认证服务实例
+func NewAuthService() *AuthService {
+	return &AuthService{
+		config:              config.Load(),
+		db:                  config.GetDB(),
+		cache:               config.GetCache(),
+		userService:         DefaultUserService(),
+		verificationService: NewVerificationService(),
+		twoFactorService:    NewTwoFactorService(),
+		auditService:        NewAuditService(),
+	}
+}
+
+// Register 注册新用户并发送邮箱验证链接，返回JWT令牌
+func (s *AuthService) Register(ctx context.Context, params RegisterParams, client ClientInfo) (string, *model.User, error) {
+	username := strings.TrimSpace(params.Username)
+	if config.SanitizeUsername(username) != username {
+		return "", nil, fmt.Errorf("%w: 只能包含字母、数字、下划线和中划线", ErrInvalidUsername)
+	}
+	if n := utf8.RuneCountInString(username); n < s.config.Security.UsernameMinLength || n > s.config.Security.UsernameMaxLength {
+		return "", nil, fmt.Errorf("%w: 长度需在%d到%d个字符之间", ErrInvalidUsername, s.config.Security.UsernameMinLength, s.config.Security.UsernameMaxLength)
+	}
+	email, ok := config.SanitizeEmail(params.Email)
+	if !ok {
+		return "", nil, ErrInvalidEmail
+	}
+	if err := config.ValidatePassword(params.Password, s.config); err != nil {
+		return "", nil, &WeakPasswordError{Reason: err.Error()}
+	}
+
+	// 在事务外哈希密码，不依赖保存钩子的判断
+	password, err := config.HashPassword(params.Password)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 用户、审计日志、验证令牌和验证邮件任务在同一事务中写入
+	var user *model.User
+	err = transaction.Run(ctx, s.db, func(ctx context.Context) error {
+		user = &model.User{
+			Username: username,
+			Email:    email,
+			Password: password,
+			Role:     model.RoleUser,
+			IsActive: model.UserActive,
+		}
+		if err := transaction.DB(ctx, s.db).Create(user).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrUserExists
+			}
+			return err
+		}
+
+		s.auditService.Record(ctx, AuditEntry{
+			Action: model.AuditRegistered,
+			UserID: user.ID,
+			Client: client,
+		})
+
+		// 验证令牌在保存点中写入，失败时不影响注册，用户可以稍后重新发送
+		if err := s.verificationService.SendVerification(ctx, user, user.Email); err != nil {
+			log.Printf("创建邮箱验证令牌失败: user=%d: %v", user.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := config.GenerateToken(user.ID, user.Email, user.TokenVersion, s.config)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, user, nil
+}
+
+// Login 使用用户名或邮箱和密码登录，返回JWT令牌
+// 同一IP失败次数过多时返回RateLimitError，账号连续失败次数过多时锁定并返回AccountLockedError
+// 用户启用了两步验证时只返回中间令牌，需通过VerifyTwoFactor换取正式令牌
+func (s *AuthService) Login(ctx context.Context, params LoginParams, client ClientInfo) (*LoginResult, error) {
+	if wait := s.ipBlocked(ctx, client.IP); wait > 0 {
+		s.auditService.Record(ctx, AuditEntry{
+			Action:  model.AuditLoginBlocked,
+			Client:  client,
+			Details: map[string]interface{}{"login": params.Login, "reason": "ip"},
+		})
+		return nil, &RateLimitError{RetryAfter: wait}
+	}
+
+	user, err := s.userService.GetUserByLogin(ctx, params.Login)
+	if err != nil {
+		if err == ErrUserNotFound {
+			s.recordIPFailure(ctx, client.IP)
+			s.auditService.Record(ctx, AuditEntry{
+				Action:  model.AuditLoginFailed,
+				Client:  client,
+				Details: map[string]interface{}{"login": params.Login},
+			})
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	// 锁定期间不校验密码，避免继续猜测
+	if user.IsLocked() {
+		s.auditService.Record(ctx, AuditEntry{
+			Action:  model.AuditLoginBlocked,
+			UserID:  user.ID,
+			Client:  client,
+			Details: map[string]interface{}{"reason": "locked"},
+		})
+		return nil, &AccountLockedError{RetryAfter: time.Until(*user.LockedUntil)}
+	}
+
+	if !user.CheckPassword(params.Password) {
+		return nil, s.loginFailed(ctx, user, client)
+	}
+	if user.IsActive != model.UserActive {
+		return nil, ErrUserDisabled
+	}
+
+	return s.startSession(ctx, user, client)
+}
+
+// VerifyTwoFactor 使用登录返回的中间令牌和验证码（或恢复码）完成登录
+// 验证码错误与密码错误一样计入账号的连续失败次数
+func (s *AuthService) VerifyTwoFactor(ctx context.Context, params TwoFactorLoginParams, client ClientInfo) (*LoginResult, error) {
+	if wait := s.ipBlocked(ctx, client.IP); wait > 0 {
+		return nil, &RateLimitError{RetryAfter: wait}
+	}
+
+	claims, err := config.ValidateToken(params.ChallengeToken, s.config)
+	if err != nil || claims.Purpose != twoFactorPurpose {
+		return nil, ErrInvalidChallenge
+	}
+
+	// 需要TOTP密钥，不能使用缓存中的用户
+	user, err := s.userService.FindUserByID(ctx, claims.UserID)
+	if err != nil {
+		if err == ErrUserNotFound {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+	if claims.TokenVersion != user.TokenVersion || !user.IsTwoFactorEnabled() {
+		return nil, ErrInvalidChallenge
+	}
+	if user.IsLocked() {
+		return nil, &AccountLockedError{RetryAfter: time.Until(*user.LockedUntil)}
+	}
+	if user.IsActive != model.UserActive {
+		return nil, ErrUserDisabled
+	}
+
+	ok, err := s.twoFactorService.VerifyCode(ctx, user, params.Code, client)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		err := s.loginFailed(ctx, user, client)
+		if err == ErrInvalidCredentials {
+			return nil, ErrInvalidTwoFactor
+		}
+		return nil, err
+	}
+	return s.completeLogin(ctx, user, client)
+}
+
+// startSession 用户身份确认后签发令牌，启用两步验证时只返回中间令牌
+func (s *AuthService) startSession(ctx context.Context, user *model.User, client ClientInfo) (*LoginResult, error) {
+	// 两步验证通过前不清除失败次数，否则知道密码的攻击者可以无限尝试验证码
+	if user.IsTwoFactorEnabled() {
+		challenge, err := config.GeneratePurposeToken(user.ID, user.Email, user.TokenVersion,
+			twoFactorPurpose, s.config.Security.TwoFactorChallengeTTL, s.config)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{User: user, ChallengeToken: challenge}, nil
+	}
+	return s.completeLogin(ctx, user, client)
+}
+
+// completeLogin 清除失败次数、记录审计日志并签发正式令牌
+func (s *AuthService) completeLogin(ctx context.Context, user *model.User, client ClientInfo) (*LoginResult, error) {
+	if err := s.userService.ResetLoginFailures(ctx, user); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(ctx, AuditEntry{
+		Action: model.AuditLoginSucceeded,
+		UserID: user.ID,
+		Client: client,
+	})
+
+	token, err := config.GenerateToken(user.ID, user.Email, user.TokenVersion, s.config)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Token: token, User: user}, nil
+}
+
+// loginFailed 记录密码错误，达到上限时锁定账号
+func (s *AuthService) loginFailed(ctx context.Context, user *model.User, client ClientInfo) error {
+	s.recordIPFailure(ctx, client.IP)
+	s.auditService.Record(ctx, AuditEntry{
+		Action: model.AuditLoginFailed,
+		UserID: user.ID,
+		Client: client,
+	})
+
+	lockedUntil, err := s.userService.RecordLoginFailure(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if lockedUntil == nil {
+		return ErrInvalidCredentials
+	}
+
+	s.auditService.Record(ctx, AuditEntry{
+		Action:  model.AuditLoginLocked,
+		UserID:  user.ID,
+		Client:  client,
+		Details: map[string]interface{}{"locked_until": lockedUntil},
+	})
+	return &AccountLockedError{RetryAfter: time.Until(*lockedUntil)}
+}
+
+// ipBlocked 检查IP在窗口期内的失败次数，超限时返回建议的等待时间
+func (s *AuthService) ipBlocked(ctx context.Context, ip string) time.Duration {
+	var failures int64
+	if err := s.cache.Get(ctx, loginIPKey(ip), &failures); err != nil {
+		return 0
+	}
+	if failures < int64(s.config.Security.LoginIPMaxAttempts) {
+		return 0
+	}
+
+	// 计数在窗口结束时过期，返回窗口剩余的时间，读不到窗口开始时间时按整个窗口计算
+	window := s.config.Security.LoginIPWindow
+	var start int64
+	if err := s.cache.Get(ctx, loginIPStartKey(ip), &start); err != nil {
+		return window
+	}
+	if wait := time.Until(time.Unix(start, 0).Add(window)); wait > 0 {
+		return min(wait, window)
+	}
+	return time.Second
+}
+
+// recordIPFailure 增加IP的登录失败计数，窗口内第一次失败时记录窗口开始时间，缓存不可用时只记录日志
+func (s *AuthService) recordIPFailure(ctx context.Context, ip string) {
+	window := s.config.Security.LoginIPWindow
+	n, err := s.cache.Increment(ctx, loginIPKey(ip), window)
+	if err != nil {
+		log.Printf("记录登录失败次数失败: %s: %v", ip, err)
+		return
+	}
+	if n == 1 {
+		if err := s.cache.Set(ctx, loginIPStartKey(ip), time.Now().Unix(), window); err != nil {
+			log.Printf("记录登录失败窗口失败: %s: %v", ip, err)
+		}
+	}
+}
+
+// loginIPKey 返回IP登录失败计数的缓存键
+func loginIPKey(ip string) string {
+	return "login:ip:" + ip
+}
+
+// loginIPStartKey 返回IP登录失败计数窗口开始时间的缓存键
+func loginIPStartKey(ip string) string {
+	return "login:ip:" + ip + ":start"
+}
+
+// Authenticate 验证JWT令牌并返回对应的用户
+func (s *AuthService) Authenticate(ctx context.Context, tokenString string) (*model.User, error) {
+	claims, err := config.ValidateToken(tokenString, s.config)
+	if err != nil {
+		return nil, err
+	}
+	// 两步验证中间令牌等临时令牌不能用于访问接口
+	if claims.Purpose != "" {
+		return nil, ErrInvalidTokenType
+	}
+
+	// 启用状态、角色和令牌版本必须读取最新值，不使用用户缓存
+	user, err := s.userService.FindUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsActive != model.UserActive {
+		return nil, ErrUserDisabled
+	}
+	// 修改密码等操作会递增TokenVersion，使此前签发的令牌失效
+	if claims.TokenVersion != user.TokenVersion {
+		return nil, ErrTokenRevoked
+	}
+	return user, nil
+}
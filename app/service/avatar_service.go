@@ -0,0 +1,295 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/NextEraAbyss/fiber-template/app/imaging"
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/storage"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AvatarURLPath 头像访问地址的路径前缀，后接 {用户ID}/{文件名}
+const AvatarURLPath = "/api/v1/avatars/"
+
+// avatarThumbnailSizes 头像缩略图的边长
+var avatarThumbnailSizes = []int{256, 64}
+
+// avatarNamePattern 头像文件名格式，原图为 {随机值}.jpg，缩略图为 {随机值}_{边长}.jpg
+var avatarNamePattern = regexp.MustCompile(`^([0-9a-f]{32})(?:_([0-9]+))?\.jpg$`)
+
+// avatarFormats 支持作为头像的图片类型
+var avatarFormats = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// 定义错误
+var (
+	ErrInvalidImage       = errors.New("无效的图片文件")
+	ErrImageTooLarge      = errors.New("图片尺寸超过限制")
+	ErrAvatarNotFound     = errors.New("头像不存在")
+	ErrAvatarUpdateDenied = errors.New("无权修改该用户的头像")
+)
+
+// AvatarURLs 头像及缩略图的访问地址，缩略图以边长为键
+type AvatarURLs struct {
+	Avatar     string            `json:"avatar"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+// AvatarService 用户头像服务
+type AvatarService struct {
+	config      *config.Config
+	db          *gorm.DB
+	storage     storage.Storage
+	userService *UserService
+}
+
+// NewAvatarService 创建新的用户头像服务实例
+func NewAvatarService() *AvatarService {
+	return &AvatarService{
+		config:      config.Load(),
+		db:          config.GetDB(),
+		storage:     config.GetStorage(),
+		userService: DefaultUserService(),
+	}
+}
+
+// Upload 上传用户头像，用户只能修改自己的头像，管理员可以修改任意用户的头像
+// 图片经过尺寸检查后按EXIF方向校正并重新编码，元数据不会保留，同时生成固定尺寸的缩略图
+func (s *AvatarService) Upload(ctx context.Context, actor *model.User, userID uint, body io.Reader, boundary string) (*AvatarURLs, error) {
+	if actor.ID != userID && actor.Role != model.RoleAdmin {
+		return nil, ErrAvatarUpdateDenied
+	}
+	if _, err := s.userService.FindUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	data, err := s.receive(body, boundary)
+	if err != nil {
+		return nil, err
+	}
+	img, err := s.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	name := hex.EncodeToString(token)
+
+	// 先保存全部文件，再更新用户记录，失败时清理已保存的文件
+	var keys []string
+	save := func(key string, encode func(w io.Writer) error) error {
+		var buf bytes.Buffer
+		if err := encode(&buf); err != nil {
+			return err
+		}
+		if err := s.storage.Put(ctx, key, &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		return nil
+	}
+	err = save(avatarKey(userID, name, 0), func(w io.Writer) error {
+		return imaging.EncodeJPEG(w, img, s.config.Upload.ImageQuality)
+	})
+	for _, size := range avatarThumbnailSizes {
+		if err != nil {
+			break
+		}
+		err = save(avatarKey(userID, name, size), func(w io.Writer) error {
+			return imaging.EncodeJPEG(w, imaging.Thumbnail(img, size), s.config.Upload.ImageQuality)
+		})
+	}
+
+	urls := s.urls(userID, name)
+	var oldAvatar string
+	if err == nil {
+		oldAvatar, err = s.setAvatar(ctx, userID, urls.Avatar)
+	}
+	if err != nil {
+		s.deleteKeys(keys)
+		return nil, err
+	}
+
+	s.deleteFiles(userID, oldAvatar)
+	return urls, nil
+}
+
+// Delete 删除用户头像，权限规则与上传相同
+func (s *AvatarService) Delete(ctx context.Context, actor *model.User, userID uint) error {
+	if actor.ID != userID && actor.Role != model.RoleAdmin {
+		return ErrAvatarUpdateDenied
+	}
+	if _, err := s.userService.FindUserByID(ctx, userID); err != nil {
+		return err
+	}
+
+	oldAvatar, err := s.setAvatar(ctx, userID, "")
+	if err != nil {
+		return err
+	}
+	s.deleteFiles(userID, oldAvatar)
+	return nil
+}
+
+// Open 打开头像文件，文件名必须符合头像命名格式，调用方负责关闭
+func (s *AvatarService) Open(ctx context.Context, userID uint, name string) (io.ReadCloser, error) {
+	if !avatarNamePattern.MatchString(name) {
+		return nil, ErrAvatarNotFound
+	}
+	r, err := s.storage.Get(ctx, fmt.Sprintf("avatars/%d/%s", userID, name))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrAvatarNotFound
+	}
+	return r, err
+}
+
+// receive 从multipart请求体中读取第一个文件
+func (s *AvatarService) receive(body io.Reader, boundary string) ([]byte, error) {
+	reader := multipart.NewReader(body, boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, ErrNoFiles
+		}
+		if err != nil {
+			return nil, ErrInvalidUpload
+		}
+		if part.FileName() == "" {
+			continue
+		}
+
+		// 多读一个字节用于判断是否超过大小限制
+		data, err := io.ReadAll(io.LimitReader(part, s.config.Upload.MaxSize+1))
+		if err != nil {
+			return nil, ErrInvalidUpload
+		}
+		if len(data) == 0 {
+			return nil, ErrEmptyFile
+		}
+		if int64(len(data)) > s.config.Upload.MaxSize {
+			return nil, ErrFileTooLarge
+		}
+		return data, nil
+	}
+}
+
+// decode 根据内容识别图片类型，检查尺寸后解码
+func (s *AvatarService) decode(data []byte) (*image.RGBA, error) {
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if !avatarFormats[mimeType] {
+		return nil, ErrFileTypeNotAllowed
+	}
+
+	info, err := imaging.DecodeInfo(data)
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if info.Width > s.config.Upload.ImageMaxWidth || info.Height > s.config.Upload.ImageMaxHeight {
+		return nil, ErrImageTooLarge
+	}
+
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	return img, nil
+}
+
+// setAvatar 更新用户头像地址，返回原来的头像地址
+func (s *AvatarService) setAvatar(ctx context.Context, userID uint, avatar string) (string, error) {
+	var oldAvatar string
+	err := transaction.Run(ctx, s.db, func(ctx context.Context) error {
+		tx := transaction.DB(ctx, s.db)
+		var user model.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "avatar").First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		oldAvatar = user.Avatar
+		return tx.Model(&user).UpdateColumn("avatar", avatar).Error
+	})
+	return oldAvatar, err
+}
+
+// deleteFiles 删除头像地址对应的原图和缩略图，不是本服务保存的头像时忽略
+// 删除失败只记录日志，用户记录已经更新，残留文件不会再被引用
+func (s *AvatarService) deleteFiles(userID uint, avatar string) {
+	if avatar == "" {
+		return
+	}
+	u, err := url.Parse(avatar)
+	if err != nil {
+		return
+	}
+	name, ok := strings.CutPrefix(u.Path, AvatarURLPath+strconv.FormatUint(uint64(userID), 10)+"/")
+	if !ok {
+		return
+	}
+	m := avatarNamePattern.FindStringSubmatch(name)
+	if m == nil || m[2] != "" {
+		return
+	}
+
+	keys := []string{avatarKey(userID, m[1], 0)}
+	for _, size := range avatarThumbnailSizes {
+		keys = append(keys, avatarKey(userID, m[1], size))
+	}
+	s.deleteKeys(keys)
+}
+
+// deleteKeys 删除存储中的文件，失败只记录日志
+func (s *AvatarService) deleteKeys(keys []string) {
+	for _, key := range keys {
+		if err := s.storage.Delete(context.Background(), key); err != nil {
+			log.Printf("删除头像文件失败: %s: %v", key, err)
+		}
+	}
+}
+
+// urls 返回头像及缩略图的访问地址
+func (s *AvatarService) urls(userID uint, name string) *AvatarURLs {
+	base := strings.TrimRight(s.config.App.URL, "/") + AvatarURLPath + strconv.FormatUint(uint64(userID), 10) + "/"
+	urls := &AvatarURLs{
+		Avatar:     base + name + ".jpg",
+		Thumbnails: make(map[string]string, len(avatarThumbnailSizes)),
+	}
+	for _, size := range avatarThumbnailSizes {
+		urls.Thumbnails[strconv.Itoa(size)] = fmt.Sprintf("%s%s_%d.jpg", base, name, size)
+	}
+	return urls
+}
+
+// avatarKey 返回头像文件的存储路径，size为0表示原图
+func avatarKey(userID uint, name string, size int) string {
+	if size == 0 {
+		return fmt.Sprintf("avatars/%d/%s.jpg", userID, name)
+	}
+	return fmt.Sprintf("avatars/%d/%s_%d.jpg", userID, name, size)
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// DownloadURLPath 签名下载地址的路径前缀，后接文件ID
+const DownloadURLPath = "/api/v1/downloads/"
+
+// 下载方式
+const (
+	DispositionAttachment = "attachment"
+	DispositionInline     = "inline"
+)
+
+// 定义错误
+var (
+	ErrDownloadLinkInvalid = errors.New("下载链接无效或已过期")
+	ErrDownloadLinkUsed    = errors.New("下载链接已被使用")
+	ErrDownloadLinkTTL     = errors.New("下载链接的有效期超过限制")
+)
+
+// CreateDownloadURLParams 创建下载链接的参数
+type CreateDownloadURLParams struct {
+	ExpiresIn   int    `json:"expires_in" validate:"omitempty,gt=0"` // 有效期，单位为秒，为空时使用默认有效期
+	SingleUse   bool   `json:"single_use"`                           // 是否只能使用一次
+	Disposition string `json:"disposition" validate:"omitempty,oneof=inline attachment"`
+	Filename    string `json:"filename" validate:"omitempty,max=255"` // 下载时使用的文件名，为空时使用上传时的文件名
+}
+
+// DownloadURL 签名下载地址
+type DownloadURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SingleUse bool      `json:"single_use"`
+}
+
+// DownloadLink 签名下载地址中的参数
+type DownloadLink struct {
+	FileID      uint64
+	Expires     int64 // 过期时间的Unix时间戳
+	Disposition string
+	Filename    string
+	Nonce       string // 一次性链接的随机值，为空表示可以重复使用
+	Signature   string
+}
+
+// DownloadService 签名下载链接服务
+// 链接包含文件ID、过期时间和下载方式等参数，以HMAC签名防止篡改，持有链接即可下载，无需认证头
+type DownloadService struct {
+	config      *config.Config
+	db          *gorm.DB
+	fileService *FileService
+}
+
+// NewDownloadService 创建新的签名下载链接服务实例
+func NewDownloadService() *DownloadService {
+	return &DownloadService{
+		config:      config.Load(),
+		db:          config.GetDB(),
+		fileService: NewFileService(),
+	}
+}
+
+// CreateURL 为用户自己的文件创建签名下载地址
+func (s *DownloadService) CreateURL(ctx context.Context, userID uint, fileID uint64, params CreateDownloadURLParams) (*DownloadURL, error) {
+	file, err := s.fileService.Get(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := s.config.Upload.SignedURLTTL
+	if params.ExpiresIn > 0 {
+		ttl = time.Duration(params.ExpiresIn) * time.Second
+	}
+	if ttl > s.config.Upload.SignedURLMaxTTL {
+		return nil, ErrDownloadLinkTTL
+	}
+
+	link := DownloadLink{
+		FileID:      file.ID,
+		Expires:     time.Now().Add(ttl).Unix(),
+		Disposition: params.Disposition,
+	}
+	if link.Disposition == "" {
+		link.Disposition = DispositionAttachment
+	}
+	if params.Filename != "" {
+		link.Filename = sanitizeFilename(params.Filename)
+	}
+	if params.SingleUse {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		link.Nonce = base64.RawURLEncoding.EncodeToString(nonce)
+	}
+	link.Signature = s.sign(link)
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(link.Expires, 10))
+	query.Set("disposition", link.Disposition)
+	if link.Filename != "" {
+		query.Set("filename", link.Filename)
+	}
+	if link.Nonce != "" {
+		query.Set("nonce", link.Nonce)
+	}
+	query.Set("signature", link.Signature)
+
+	return &DownloadURL{
+		URL:       strings.TrimRight(s.config.App.URL, "/") + DownloadURLPath + strconv.FormatUint(file.ID, 10) + "?" + query.Encode(),
+		ExpiresAt: time.Unix(link.Expires, 0),
+		SingleUse: params.SingleUse,
+	}, nil
+}
+
+// Resolve 验证下载链接并返回对应的文件
+// consume为true时一次性链接被标记为已使用，HEAD等不返回内容的请求应传入false
+func (s *DownloadService) Resolve(ctx context.Context, link DownloadLink, consume bool) (*model.File, error) {
+	// 签名内容以换行分隔，创建链接时文件名中的控制字符已被清理
+	if link.Disposition != DispositionAttachment && link.Disposition != DispositionInline || strings.Contains(link.Filename, "\n") {
+		return nil, ErrDownloadLinkInvalid
+	}
+	if !hmac.Equal([]byte(s.sign(link)), []byte(link.Signature)) {
+		return nil, ErrDownloadLinkInvalid
+	}
+	if time.Now().Unix() >= link.Expires {
+		return nil, ErrDownloadLinkInvalid
+	}
+
+	var file model.File
+	if err := s.db.WithContext(ctx).First(&file, link.FileID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+
+	if link.Nonce != "" && consume {
+		// 使用记录保存在数据库中，所有节点共享；记录保留到链接过期，之后签名校验即会失败
+		err := s.db.WithContext(ctx).Create(&model.DownloadNonce{Nonce: link.Nonce, ExpiresAt: time.Unix(link.Expires, 0)}).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrDownloadLinkUsed
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &file, nil
+}
+
+// sign 计算下载链接的签名
+func (s *DownloadService) sign(link DownloadLink) string {
+	mac := hmac.New(sha256.New, []byte(s.config.Upload.SigningKey))
+	mac.Write([]byte(strings.Join([]string{
+		"download",
+		strconv.FormatUint(link.FileID, 10),
+		strconv.FormatInt(link.Expires, 10),
+		link.Disposition,
+		link.Filename,
+		link.Nonce,
+	}, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
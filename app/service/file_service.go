@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/NextEraAbyss/fiber-template/app/filter"
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/pagination"
+	"github.com/NextEraAbyss/fiber-template/app/storage"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sniffLen 识别文件类型需要读取的字节数，与http.DetectContentType一致
+const sniffLen = 512
+
+// 定义错误
+var (
+	ErrFileNotFound       = errors.New("文件不存在")
+	ErrInvalidUpload      = errors.New("无效的上传请求")
+	ErrNoFiles            = errors.New("请选择要上传的文件")
+	ErrTooManyFiles       = errors.New("上传文件数量超过限制")
+	ErrEmptyFile          = errors.New("文件内容为空")
+	ErrFileTooLarge       = errors.New("文件大小超过限制")
+	ErrFileTypeNotAllowed = errors.New("不支持的文件类型")
+)
+
+// FileService 文件上传服务
+type FileService struct {
+	config  *config.Config
+	db      *gorm.DB
+	storage storage.Storage
+}
+
+// NewFileService 创建新的文件上传服务实例
+func NewFileService() *FileService {
+	return &FileService{
+		config:  config.Load(),
+		db:      config.GetDB(),
+		storage: config.GetStorage(),
+	}
+}
+
+// pendingFile 已写入临时目录、尚未保存的上传文件
+type pendingFile struct {
+	tmpPath  string
+	name     string
+	mimeType string
+	size     int64
+	checksum string
+}
+
+// Upload 流式读取multipart请求体中的文件并保存，返回创建的文件记录
+// 所有文件先写入临时目录并通过大小、类型和数量检查，任一文件不合格时整个请求失败
+func (s *FileService) Upload(ctx context.Context, user *model.User, body io.Reader, boundary string) ([]model.File, error) {
+	pending, err := s.receive(body, boundary)
+	defer func() {
+		for _, p := range pending {
+			os.Remove(p.tmpPath)
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, ErrNoFiles
+	}
+
+	return s.create(ctx, user.ID, pending)
+}
+
+// FileListOptions 文件列表的分页和排序选项
+var FileListOptions = pagination.Options{
+	Sorts:       []string{"name", "size", "created_at"},
+	DefaultSort: "-id",
+}
+
+// FileFilters 文件列表允许筛选的字段
+var FileFilters = filter.Fields{
+	"name":       {Type: filter.String},
+	"mime_type":  {Type: filter.String},
+	"size":       {Type: filter.Int},
+	"created_at": {Type: filter.Time},
+}
+
+// List 获取用户上传的文件，scopes用于添加筛选条件和选择查询的列
+func (s *FileService) List(ctx context.Context, userID uint, page *pagination.Request, scopes ...func(*gorm.DB) *gorm.DB) ([]model.File, *pagination.Page, error) {
+	return pagination.Find[model.File](s.db.WithContext(ctx).Where("user_id = ?", userID).Scopes(scopes...), page)
+}
+
+// Get 获取属于指定用户的文件
+func (s *FileService) Get(ctx context.Context, userID uint, id uint64) (*model.File, error) {
+	var file model.File
+	err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&file).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Open 打开文件内容，调用方负责关闭
+func (s *FileService) Open(ctx context.Context, file *model.File) (io.ReadCloser, error) {
+	r, err := s.storage.Get(ctx, file.Path)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrFileNotFound
+	}
+	return r, err
+}
+
+// OpenRange 打开文件中从offset开始的length个字节，调用方负责关闭
+func (s *FileService) OpenRange(ctx context.Context, file *model.File, offset, length int64) (io.ReadCloser, error) {
+	r, err := s.storage.GetRange(ctx, file.Path, offset, length)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrFileNotFound
+	}
+	return r, err
+}
+
+// Delete 删除文件记录，没有其他记录引用同一内容时一并删除存储的文件
+func (s *FileService) Delete(ctx context.Context, userID uint, id uint64) error {
+	file, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.WithContext(ctx).Delete(file).Error; err != nil {
+		return err
+	}
+	return s.release(ctx, file.Disk, file.Path)
+}
+
+// release 在内容没有被任何文件记录引用时删除存储对象
+// 持有内容的行锁时计数，并发保存相同内容的请求要么已提交记录，要么在删除后重新写入对象
+func (s *FileService) release(ctx context.Context, disk, path string) error {
+	return transaction.Run(ctx, s.db, func(ctx context.Context) error {
+		tx := transaction.DB(ctx, s.db)
+		if err := lockContent(tx, disk, path); err != nil {
+			return err
+		}
+
+		var refs int64
+		if err := tx.Model(&model.File{}).Where("disk = ? AND path = ?", disk, path).Count(&refs).Error; err != nil {
+			return err
+		}
+		if refs > 0 {
+			return nil
+		}
+		return s.storage.Delete(ctx, path)
+	})
+}
+
+// receive 读取请求体中的所有文件到临时目录，出错时返回已接收的文件以便清理
+func (s *FileService) receive(body io.Reader, boundary string) ([]*pendingFile, error) {
+	reader := multipart.NewReader(body, boundary)
+
+	var pending []*pendingFile
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return pending, nil
+		}
+		if err != nil {
+			return pending, ErrInvalidUpload
+		}
+		// 普通表单字段在读取下一部分时被跳过
+		if part.FileName() == "" {
+			continue
+		}
+		if len(pending) >= s.config.Upload.MaxFiles {
+			return pending, ErrTooManyFiles
+		}
+
+		p, err := s.receivePart(part)
+		if err != nil {
+			return pending, err
+		}
+		pending = append(pending, p)
+	}
+}
+
+// receivePart 将一个文件写入临时目录，同时计算哈希并根据内容识别类型
+func (s *FileService) receivePart(part *multipart.Part) (p *pendingFile, err error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, ErrInvalidUpload
+	}
+	head = head[:n]
+	if n == 0 {
+		return nil, ErrEmptyFile
+	}
+
+	// 不信任客户端声明的Content-Type，只根据内容识别
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !s.allowedType(mimeType) {
+		return nil, ErrFileTypeNotAllowed
+	}
+
+	tmp, err := os.CreateTemp(s.config.Upload.TempPath, "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tmp.Close()
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	hash := sha256.New()
+	w := io.MultiWriter(tmp, hash)
+	if _, err := w.Write(head); err != nil {
+		return nil, err
+	}
+	// 多读一个字节用于判断是否超过大小限制
+	copied, err := io.Copy(w, io.LimitReader(part, s.config.Upload.MaxSize-int64(n)+1))
+	if err != nil {
+		return nil, ErrInvalidUpload
+	}
+	size := int64(n) + copied
+	if size > s.config.Upload.MaxSize {
+		return nil, ErrFileTooLarge
+	}
+
+	return &pendingFile{
+		tmpPath:  tmp.Name(),
+		name:     sanitizeFilename(part.FileName()),
+		mimeType: mimeType,
+		size:     size,
+		checksum: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// create 保存已接收的文件并创建文件记录
+// 在事务中对每份内容加锁直到记录提交，避免复用的存储对象被并发的删除请求删除
+func (s *FileService) create(ctx context.Context, userID uint, pending []*pendingFile) ([]model.File, error) {
+	disk := s.config.Upload.Driver
+	// 按哈希顺序加锁，避免并发上传同一组文件时死锁
+	sorted := slices.SortedFunc(slices.Values(pending), func(a, b *pendingFile) int {
+		return strings.Compare(a.checksum, b.checksum)
+	})
+
+	var files []model.File
+	err := transaction.Run(ctx, s.db, func(ctx context.Context) error {
+		tx := transaction.DB(ctx, s.db)
+		// 存储对象以内容哈希为键，事务重试时重复写入不会产生副作用
+		for _, p := range sorted {
+			if err := lockContent(tx, disk, contentKey(p.checksum)); err != nil {
+				return err
+			}
+			if err := s.store(ctx, contentKey(p.checksum), p); err != nil {
+				return err
+			}
+		}
+
+		files = make([]model.File, 0, len(pending))
+		for _, p := range pending {
+			files = append(files, model.File{
+				UserID:   userID,
+				Name:     p.name,
+				MimeType: p.mimeType,
+				Size:     p.size,
+				Checksum: p.checksum,
+				Disk:     disk,
+				Path:     contentKey(p.checksum),
+			})
+		}
+		// 写入记录失败时已保存的内容不会被引用，内容相同的文件再次上传时会复用
+		return tx.Create(&files).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// store 将临时文件保存到存储中，内容相同的文件已存在时直接复用
+func (s *FileService) store(ctx context.Context, key string, p *pendingFile) error {
+	_, err := s.storage.Stat(ctx, key)
+	if err == nil || !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	f, err := os.Open(p.tmpPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.storage.Put(ctx, key, f, p.size, p.mimeType)
+}
+
+// lockContent 对内容记录加行锁，需在事务中调用，记录不存在时先创建
+func lockContent(tx *gorm.DB, disk, path string) error {
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.FileContent{Disk: disk, Path: path}).Error
+	if err != nil {
+		return err
+	}
+	var content model.FileContent
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("disk = ? AND path = ?", disk, path).Take(&content).Error
+}
+
+// allowedType 判断文件类型是否允许上传，支持 image/* 形式的通配
+func (s *FileService) allowedType(mimeType string) bool {
+	for _, allowed := range s.config.Upload.AllowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mimeType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// contentKey 根据内容哈希生成存储路径，使用两级目录避免单个目录下文件过多
+func contentKey(checksum string) string {
+	return checksum[:2] + "/" + checksum[2:4] + "/" + checksum
+}
+
+// sanitizeFilename 清理文件名中的控制字符和路径分隔符
+func sanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '/' || r == '\\' {
+			return -1
+		}
+		return r
+	}, name)
+	name = truncate(strings.TrimSpace(name), 255)
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	return name
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/schedule"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// 定义错误
+var (
+	ErrJobNotFound = errors.New("定时任务不存在")
+)
+
+// JobInfo 定时任务信息
+type JobInfo struct {
+	Name      string        `json:"name"`
+	Schedule  string        `json:"schedule"`
+	Singleton bool          `json:"singleton"`
+	Paused    bool          `json:"paused"`
+	NextRun   *time.Time    `json:"next_run"`
+	LastRun   *model.JobRun `json:"last_run"`
+}
+
+// JobService 定时任务管理服务
+type JobService struct {
+	db *gorm.DB
+}
+
+// NewJobService 创建新的定时任务管理服务实例
+func NewJobService() *JobService {
+	return &JobService{
+		db: config.GetDB(),
+	}
+}
+
+// GetJobs 获取所有已注册的定时任务
+func (s *JobService) GetJobs(ctx context.Context) ([]JobInfo, error) {
+	tasks := config.GetTasks()
+	jobs := make([]JobInfo, 0, len(tasks))
+	for _, task := range tasks {
+		job, err := s.jobInfo(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// GetJob 获取单个定时任务
+func (s *JobService) GetJob(ctx context.Context, name string) (*JobInfo, error) {
+	task, ok := config.FindTask(name)
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	job, err := s.jobInfo(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetJobRuns 获取定时任务最近的执行记录
+func (s *JobService) GetJobRuns(ctx context.Context, name string, limit int) ([]model.JobRun, error) {
+	if _, ok := config.FindTask(name); !ok {
+		return nil, ErrJobNotFound
+	}
+
+	var runs []model.JobRun
+	err := s.db.WithContext(ctx).
+		Where("job = ?", name).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
+
+// TriggerJob 立即执行一次定时任务
+func (s *JobService) TriggerJob(name string) error {
+	task, ok := config.FindTask(name)
+	if !ok {
+		return ErrJobNotFound
+	}
+	return task.Trigger()
+}
+
+// PauseJob 暂停定时任务
+func (s *JobService) PauseJob(ctx context.Context, name string) error {
+	task, ok := config.FindTask(name)
+	if !ok {
+		return ErrJobNotFound
+	}
+	return task.Pause(ctx)
+}
+
+// ResumeJob 恢复定时任务
+func (s *JobService) ResumeJob(ctx context.Context, name string) error {
+	task, ok := config.FindTask(name)
+	if !ok {
+		return ErrJobNotFound
+	}
+	return task.Resume(ctx)
+}
+
+// jobInfo 汇总定时任务的状态和最近一次执行记录
+func (s *JobService) jobInfo(ctx context.Context, task config.ScheduleTask) (JobInfo, error) {
+	paused, err := task.Paused(ctx)
+	if err != nil {
+		return JobInfo{}, err
+	}
+
+	job := JobInfo{
+		Name:      task.Name(),
+		Schedule:  task.Schedule(),
+		Singleton: task.Options().Singleton,
+		Paused:    paused,
+	}
+	if next := task.NextRun(); !next.IsZero() {
+		job.NextRun = &next
+	}
+
+	var lastRun model.JobRun
+	err = s.db.WithContext(ctx).Where("job = ?", job.Name).Order("started_at DESC").Take(&lastRun).Error
+	if err == nil {
+		job.LastRun = &lastRun
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return JobInfo{}, err
+	}
+
+	return job, nil
+}
+
+// IsJobConflict 判断是否为任务正在执行或锁被占用导致的触发失败
+func IsJobConflict(err error) bool {
+	return errors.Is(err, schedule.ErrJobRunning) || errors.Is(err, schedule.ErrLockHeld)
+}
@@ -0,0 +1,14 @@
+package service
+
+import (
+	"context"
+
+	"github.com/NextEraAbyss/fiber-template/app/queue"
+)
+
+// RegisterJobs 注册服务层的后台任务处理器，需在队列启动前调用
+func RegisterJobs(q *queue.Queue) {
+	queue.Handle(q, PasswordResetJobType, func(ctx context.Context, params ForgotPasswordParams) error {
+		return NewPasswordService().SendResetLink(ctx, params)
+	})
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/NextEraAbyss/fiber-template/app/mail"
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"github.com/NextEraAbyss/fiber-template/config"
+)
+
+// 内置邮件模板名称
+const (
+	MailWelcome       = "welcome"
+	MailVerifyEmail   = "verify_email"
+	MailPasswordReset = "password_reset"
+	MailEmailChange   = "email_change"
+)
+
+// ErrMailTemplateNotFound 邮件模板不存在
+var ErrMailTemplateNotFound = errors.New("邮件模板不存在")
+
+// MailService 模板邮件服务
+type MailService struct {
+	config   *config.Config
+	mailer   mail.Mailer
+	renderer *mail.Renderer
+}
+
+// NewMailService 创建新的模板邮件服务实例
+func NewMailService() *MailService {
+	return &MailService{
+		config:   config.Load(),
+		mailer:   config.GetMailer(),
+		renderer: config.GetMailRenderer(),
+	}
+}
+
+// Send 按指定语言渲染模板并发送给收件人
+// 使用队列时任务随事务一起提交，否则在事务提交后发送
+func (s *MailService) Send(ctx context.Context, to, locale, template string, data map[string]interface{}) error {
+	msg, err := s.Render(template, locale, data)
+	if err != nil {
+		return err
+	}
+	msg.To = []string{to}
+
+	// 不经过队列的邮件在事务提交后发送，事务回滚时不会发出
+	if _, queued := s.mailer.(*mail.QueuedMailer); !queued && transaction.Active(ctx) {
+		transaction.AfterCommit(ctx, func(ctx context.Context) {
+			if err := s.mailer.Send(ctx, msg); err != nil {
+				log.Printf("发送邮件失败: %s: %v", template, err)
+			}
+		})
+		return nil
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+// SendToUser 使用用户的语言渲染模板并发送到用户邮箱
+// 模板数据中会自动加入 Username 和 Email
+func (s *MailService) SendToUser(ctx context.Context, user *model.User, template string, data map[string]interface{}) error {
+	return s.SendToAddress(ctx, user, user.Email, template, data)
+}
+
+// SendToAddress 与SendToUser相同，但发送到指定地址，用于验证尚未生效的新邮箱
+func (s *MailService) SendToAddress(ctx context.Context, user *model.User, to, template string, data map[string]interface{}) error {
+	merged := map[string]interface{}{
+		"Username": user.Username,
+		"Email":    to,
+	}
+	for key, value := range data {
+		merged[key] = value
+	}
+	return s.Send(ctx, to, s.userLocale(user), template, merged)
+}
+
+// Render 渲染模板但不发送，用于预览
+func (s *MailService) Render(template, locale string, data map[string]interface{}) (*mail.Message, error) {
+	msg, err := s.renderer.Render(template, locale, data)
+	if errors.Is(err, mail.ErrTemplateNotFound) {
+		return nil, ErrMailTemplateNotFound
+	}
+	return msg, err
+}
+
+// Templates 返回所有模板名称和支持的语言
+func (s *MailService) Templates() ([]string, []string) {
+	return s.renderer.Names(), s.renderer.Locales()
+}
+
+// userLocale 返回用户的语言，未设置时使用应用默认语言
+func (s *MailService) userLocale(user *model.User) string {
+	if user.Locale != "" {
+		return user.Locale
+	}
+	return s.config.App.Locale
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NextEraAbyss/fiber-template/app/cache"
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/oidc"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// 定义错误
+var (
+	ErrOIDCProviderNotFound  = errors.New("不支持的登录方式")
+	ErrOIDCInvalidState      = errors.New("登录请求已过期，请重新登录")
+	ErrOIDCLoginFailed       = errors.New("第三方登录失败")
+	ErrOIDCEmailNotVerified  = errors.New("第三方账号未提供已验证的邮箱")
+	ErrOIDCAccountUnverified = errors.New("该邮箱已注册但尚未验证，请先使用密码登录并验证邮箱")
+	ErrOIDCNotRegistered     = errors.New("该邮箱尚未注册")
+)
+
+// OIDCCallbackParams 身份提供方回调参数
+type OIDCCallbackParams struct {
+	Code             string
+	State            string
+	Error            string // 用户拒绝授权等情况下身份提供方返回的错误码
+	ErrorDescription string
+}
+
+// oidcState 保存在缓存中的登录请求，回调时使用一次后删除
+type oidcState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// OIDCService OpenID Connect登录服务
+type OIDCService struct {
+	config       *config.Config
+	db           *gorm.DB
+	cache        cache.Store
+	userService  *UserService
+	authService  *AuthService
+	auditService *AuditService
+}
+
+// NewOIDCService 创建新的OpenID Connect登录服务实例
+func NewOIDCService() *OIDCService {
+	return &OIDCService{
+		config:       config.Load(),
+		db:           config.GetDB(),
+		cache:        config.GetCache(),
+		userService:  DefaultUserService(),
+		authService:  NewAuthService(),
+		auditService: NewAuditService(),
+	}
+}
+
+// Providers 返回已配置的身份提供方名称
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.config.OIDC.Providers))
+	for _, p := range s.config.OIDC.Providers {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// Begin 创建登录请求并返回身份提供方的授权地址和state
+// 调用方应将state保存在浏览器Cookie中，回调时校验，防止登录CSRF
+func (s *OIDCService) Begin(ctx context.Context, name string) (string, string, error) {
+	provider, ok := config.GetOIDCProvider(name)
+	if !ok {
+		return "", "", ErrOIDCProviderNotFound
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	pending := oidcState{Provider: name}
+	if pending.Nonce, err = oidc.RandomString(); err != nil {
+		return "", "", err
+	}
+	if pending.Verifier, err = oidc.RandomString(); err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, pending.Nonce, pending.Verifier)
+	if err != nil {
+		log.Printf("OIDC提供方 %s 不可用: %v", name, err)
+		return "", "", ErrOIDCLoginFailed
+	}
+	if err := s.cache.Set(ctx, oidcStateKey(state), pending, s.config.OIDC.StateTTL); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// Callback 处理身份提供方回调，校验state后换取并验证ID令牌，再按外部身份或已验证邮箱找到用户并登录
+func (s *OIDCService) Callback(ctx context.Context, name string, params OIDCCallbackParams, client ClientInfo) (*LoginResult, error) {
+	provider, ok := config.GetOIDCProvider(name)
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	var pending oidcState
+	if params.State == "" || s.cache.Get(ctx, oidcStateKey(params.State), &pending) != nil || pending.Provider != name {
+		return nil, ErrOIDCInvalidState
+	}
+	// state只能使用一次
+	if err := s.cache.Delete(ctx, oidcStateKey(params.State)); err != nil {
+		return nil, err
+	}
+
+	if params.Error != "" {
+		log.Printf("OIDC提供方 %s 返回错误: %s %s", name, params.Error, params.ErrorDescription)
+		return nil, ErrOIDCLoginFailed
+	}
+	if params.Code == "" {
+		return nil, ErrOIDCLoginFailed
+	}
+
+	token, err := provider.Exchange(ctx, params.Code, pending.Verifier)
+	if err != nil {
+		log.Printf("OIDC提供方 %s 登录失败: %v", name, err)
+		return nil, ErrOIDCLoginFailed
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, pending.Nonce)
+	if err != nil || claims.Subject == "" {
+		log.Printf("OIDC提供方 %s ID令牌验证失败: %v", name, err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	user, err := s.resolveUser(ctx, name, claims, client)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsActive != model.UserActive {
+		return nil, ErrUserDisabled
+	}
+	return s.authService.startSession(ctx, user, client)
+}
+
+// resolveUser 查找外部身份对应的用户，首次登录时按已验证邮箱关联已有用户或创建新用户
+// 只关联邮箱已验证的本地用户，避免他人预先用该邮箱注册后接管第三方登录的账号
+func (s *OIDCService) resolveUser(ctx context.Context, name string, claims *oidc.Claims, client ClientInfo) (*model.User, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+
+	var identity model.UserIdentity
+	err := db.Where("provider = ? AND subject = ?", name, claims.Subject).First(&identity).Error
+	if err == nil {
+		user, err := s.userService.FindUserByID(ctx, identity.UserID)
+		if err != nil {
+			if err == ErrUserNotFound {
+				return nil, ErrUserDisabled
+			}
+			return nil, err
+		}
+		if err := db.Model(&identity).Updates(map[string]interface{}{"email": claims.Email, "last_login_at": now}).Error; err != nil {
+			log.Printf("更新第三方身份失败: %d: %v", identity.ID, err)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	email, ok := config.SanitizeEmail(claims.Email)
+	if !ok || !bool(claims.EmailVerified) {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	linked := true
+	user, err := s.userService.GetUserByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if !user.IsEmailVerified() {
+			return nil, ErrOIDCAccountUnverified
+		}
+	case err == ErrUserNotFound:
+		if !s.config.OIDC.AutoRegister {
+			return nil, ErrOIDCNotRegistered
+		}
+		if user, err = s.createUser(ctx, claims, email); err != nil {
+			return nil, err
+		}
+		linked = false
+	default:
+		return nil, err
+	}
+
+	identity = model.UserIdentity{
+		UserID:      user.ID,
+		Provider:    name,
+		Subject:     claims.Subject,
+		Email:       email,
+		LastLoginAt: &now,
+	}
+	if err := db.Create(&identity).Error; err != nil {
+		// 同一身份的并发回调已完成关联
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	if linked {
+		s.auditService.Record(ctx, AuditEntry{
+			Action:  model.AuditIdentityLinked,
+			UserID:  user.ID,
+			Client:  client,
+			Details: map[string]interface{}{"provider": name, "subject": claims.Subject},
+		})
+	}
+	return user, nil
+}
+
+// createUser 为第三方账号创建用户，邮箱已由身份提供方验证，密码为随机值，用户可通过重置密码设置
+func (s *OIDCService) createUser(ctx context.Context, claims *oidc.Claims, email string) (*model.User, error) {
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return nil, err
+	}
+
+	base := s.usernameFrom(claims, email)
+	now := time.Now()
+	for attempt := 0; attempt < 5; attempt++ {
+		username := base
+		if attempt > 0 {
+			suffix := make([]byte, 3)
+			if _, err := rand.Read(suffix); err != nil {
+				return nil, err
+			}
+			username = truncate(base, s.config.Security.UsernameMaxLength-7) + "-" + hex.EncodeToString(suffix)
+		}
+
+		user := &model.User{
+			Username:        username,
+			Email:           email,
+			Password:        base64.RawURLEncoding.EncodeToString(password),
+			Role:            model.RoleUser,
+			IsActive:        model.UserActive,
+			EmailVerifiedAt: &now,
+		}
+		err := s.db.WithContext(ctx).Create(user).Error
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, err
+		}
+		// 邮箱冲突说明同一邮箱的并发登录已创建用户
+		if existing, err := s.userService.GetUserByEmail(ctx, email); err == nil {
+			return existing, nil
+		}
+	}
+	return nil, ErrUserExists
+}
+
+// usernameFrom 根据第三方账号信息生成符合规则的用户名
+func (s *OIDCService) usernameFrom(claims *oidc.Claims, email string) string {
+	candidate := claims.PreferredUsername
+	if candidate == "" || strings.Contains(candidate, "@") {
+		candidate, _, _ = strings.Cut(email, "@")
+	}
+	username := truncate(config.SanitizeUsername(candidate), s.config.Security.UsernameMaxLength)
+	if username == "" {
+		username = "user"
+	}
+	for utf8.RuneCountInString(username) < s.config.Security.UsernameMinLength {
+		username += "_"
+	}
+	return username
+}
+
+// oidcStateKey 返回登录请求的缓存键
+func oidcStateKey(state string) string {
+	return "oidc:state:" + state
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/cache"
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/oidc"
+	"github.com/NextEraAbyss/fiber-template/app/oidc/oidctest"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// setupOIDC 使用内存数据库、内存缓存和测试身份提供方创建OIDCService
+func setupOIDC(t *testing.T) (*OIDCService, *oidctest.Server) {
+	t.Helper()
+
+	db := openTestDB(t, &model.User{}, &model.UserIdentity{}, &model.AuditLog{})
+	idp := oidctest.NewServer(t)
+	config.DB = db
+	config.Cache = cache.New(cache.NewMemoryDriver(100), "test:", time.Minute, false)
+	config.OIDCProviders = map[string]*oidc.Provider{
+		"test": oidc.NewProvider(oidc.Config{
+			Name:        "test",
+			Issuer:      idp.URL,
+			ClientID:    "client-1",
+			RedirectURL: "http://localhost/api/v1/auth/oidc/test/callback",
+		}, idp.Client()),
+	}
+	return NewOIDCService(), idp
+}
+
+// oidcLogin 走完一次登录流程：Begin、在身份提供方登录、回调
+func oidcLogin(t *testing.T, s *OIDCService, idp *oidctest.Server, claims jwt.MapClaims) (*LoginResult, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	authURL, state, err := s.Begin(ctx, "test")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, returned := idp.Authorize(t, authURL, claims)
+	if returned != state {
+		t.Fatalf("state = %q, want %q", returned, state)
+	}
+	return s.Callback(ctx, "test", OIDCCallbackParams{Code: code, State: state}, ClientInfo{IP: "127.0.0.1"})
+}
+
+func TestOIDCLoginRegistersUser(t *testing.T) {
+	s, idp := setupOIDC(t)
+
+	result, err := oidcLogin(t, s, idp, jwt.MapClaims{
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"preferred_username": "alice",
+	})
+	if err != nil {
+		t.Fatalf("Callback: %v", err)
+	}
+	if result.Token == "" || result.User.Email != "alice@example.com" || !result.User.IsEmailVerified() {
+		t.Fatalf("result = %+v", result)
+	}
+
+	var identity model.UserIdentity
+	if err := s.db.Where("provider = ? AND subject = ?", "test", "subject-1").First(&identity).Error; err != nil {
+		t.Fatalf("身份未关联: %v", err)
+	}
+	if identity.UserID != result.User.ID {
+		t.Fatalf("identity.UserID = %d, want %d", identity.UserID, result.User.ID)
+	}
+
+	// 再次登录通过外部身份找到同一个用户
+	again, err := oidcLogin(t, s, idp, jwt.MapClaims{"email": "alice@example.com", "email_verified": true})
+	if err != nil {
+		t.Fatalf("Callback: %v", err)
+	}
+	if again.User.ID != result.User.ID {
+		t.Fatalf("second login user = %d, want %d", again.User.ID, result.User.ID)
+	}
+}
+
+func TestOIDCLinksVerifiedUser(t *testing.T) {
+	s, idp := setupOIDC(t)
+
+	now := time.Now()
+	user := &model.User{Username: "bob", Email: "bob@example.com", Password: "x", Role: model.RoleUser, IsActive: model.UserActive, EmailVerifiedAt: &now}
+	if err := s.db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := oidcLogin(t, s, idp, jwt.MapClaims{"email": "Bob@Example.com", "email_verified": true})
+	if err != nil {
+		t.Fatalf("Callback: %v", err)
+	}
+	if result.User.ID != user.ID {
+		t.Fatalf("user = %d, want %d", result.User.ID, user.ID)
+	}
+
+	var linked int64
+	s.db.Model(&model.AuditLog{}).Where("action = ? AND user_id = ?", model.AuditIdentityLinked, user.ID).Count(&linked)
+	if linked != 1 {
+		t.Fatalf("identity.linked audit logs = %d, want 1", linked)
+	}
+}
+
+func TestOIDCRejectsUnverifiedEmail(t *testing.T) {
+	s, idp := setupOIDC(t)
+
+	_, err := oidcLogin(t, s, idp, jwt.MapClaims{"email": "carol@example.com", "email_verified": false})
+	if !errors.Is(err, ErrOIDCEmailNotVerified) {
+		t.Fatalf("err = %v, want %v", err, ErrOIDCEmailNotVerified)
+	}
+
+	var users int64
+	s.db.Model(&model.User{}).Count(&users)
+	if users != 0 {
+		t.Fatalf("users = %d, want 0", users)
+	}
+}
+
+func TestOIDCDoesNotLinkUnverifiedUser(t *testing.T) {
+	s, idp := setupOIDC(t)
+
+	user := &model.User{Username: "dave", Email: "dave@example.com", Password: "x", Role: model.RoleUser, IsActive: model.UserActive}
+	if err := s.db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := oidcLogin(t, s, idp, jwt.MapClaims{"email": "dave@example.com", "email_verified": true})
+	if !errors.Is(err, ErrOIDCAccountUnverified) {
+		t.Fatalf("err = %v, want %v", err, ErrOIDCAccountUnverified)
+	}
+
+	var identities int64
+	s.db.Model(&model.UserIdentity{}).Count(&identities)
+	if identities != 0 {
+		t.Fatalf("identities = %d, want 0", identities)
+	}
+}
+
+func TestOIDCRejectsInvalidIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+	}{
+		{"nonce mismatch", jwt.MapClaims{"nonce": "other"}},
+		{"wrong audience", jwt.MapClaims{"aud": "other-client"}},
+		{"wrong issuer", jwt.MapClaims{"iss": "https://evil.example.com"}},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-2 * time.Minute).Unix()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, idp := setupOIDC(t)
+			tt.claims["email"] = "erin@example.com"
+			tt.claims["email_verified"] = true
+
+			_, err := oidcLogin(t, s, idp, tt.claims)
+			if !errors.Is(err, ErrOIDCLoginFailed) {
+				t.Fatalf("err = %v, want %v", err, ErrOIDCLoginFailed)
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackState(t *testing.T) {
+	s, idp := setupOIDC(t)
+	ctx := context.Background()
+	client := ClientInfo{IP: "127.0.0.1"}
+
+	authURL, state, err := s.Begin(ctx, "test")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	code, _ := idp.Authorize(t, authURL, jwt.MapClaims{"email": "frank@example.com", "email_verified": true})
+
+	// 未知的state
+	if _, err := s.Callback(ctx, "test", OIDCCallbackParams{Code: code, State: "forged"}, client); !errors.Is(err, ErrOIDCInvalidState) {
+		t.Fatalf("forged state: err = %v, want %v", err, ErrOIDCInvalidState)
+	}
+
+	if _, err := s.Callback(ctx, "test", OIDCCallbackParams{Code: code, State: state}, client); err != nil {
+		t.Fatalf("Callback: %v", err)
+	}
+
+	// state只能使用一次
+	if _, err := s.Callback(ctx, "test", OIDCCallbackParams{Code: code, State: state}, client); !errors.Is(err, ErrOIDCInvalidState) {
+		t.Fatalf("reused state: err = %v, want %v", err, ErrOIDCInvalidState)
+	}
+}
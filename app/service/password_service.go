@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/queue"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// PasswordResetJobType 发送重置密码邮件的队列任务类型
+const PasswordResetJobType = "password:reset"
+
+// ForgotPasswordParams 忘记密码参数
+type ForgotPasswordParams struct {
+	Email string `json:"email" validate:"required,email,max=100"`
+}
+
+// ResetPasswordParams 重置密码参数
+type ResetPasswordParams struct {
+	Token    string `json:"token" validate:"required,max=100"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+// WeakPasswordError 密码不满足安全策略
+type WeakPasswordError struct {
+	Reason string
+}
+
+func (e *WeakPasswordError) Error() string {
+	return e.Reason
+}
+
+// PasswordService 密码找回服务
+type PasswordService struct {
+	config       *config.Config
+	db           *gorm.DB
+	userService  *UserService
+	tokenService *UserTokenService
+	mailService  *MailService
+	queue        *queue.Queue
+}
+
+// NewPasswordService 创建新的密码找回服务实例
+func NewPasswordService() *PasswordService {
+	return &PasswordService{
+		config:       config.Load(),
+		db:           config.GetDB(),
+		userService:  DefaultUserService(),
+		tokenService: NewUserTokenService(),
+		mailService:  NewMailService(),
+		queue:        config.GetQueue(),
+	}
+}
+
+// ForgotPassword 投递发送重置密码邮件的后台任务
+// 为避免通过响应内容或响应时间泄露邮箱是否已注册，查询用户、签发令牌和发送邮件都在任务中进行
+func (s *PasswordService) ForgotPassword(ctx context.Context, params ForgotPasswordParams) error {
+	return s.queue.Dispatch(ctx, PasswordResetJobType, params)
+}
+
+// SendResetLink 向邮箱对应的用户发送重置密码邮件，邮箱不存在或用户被禁用时不发送
+func (s *PasswordService) SendResetLink(ctx context.Context, params ForgotPasswordParams) error {
+	user, err := s.userService.GetUserByEmail(ctx, params.Email)
+	if err != nil {
+		if err == ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+	if user.IsActive != model.UserActive {
+		return nil
+	}
+
+	ttl := s.config.Security.PasswordResetTTL
+	token, err := s.tokenService.Issue(ctx, user, model.TokenPurposePasswordReset, user.Email, ttl)
+	if err != nil {
+		return err
+	}
+
+	return s.mailService.SendToUser(ctx, user, MailPasswordReset, map[string]interface{}{
+		"URL":            s.resetURL(token),
+		"ExpiresMinutes": int(ttl.Minutes()),
+	})
+}
+
+// ResetPassword 使用重置令牌设置新密码，成功后令牌失效且用户已登录的会话全部失效
+func (s *PasswordService) ResetPassword(ctx context.Context, params ResetPasswordParams) error {
+	if err := config.ValidatePassword(params.Password, s.config); err != nil {
+		return &WeakPasswordError{Reason: err.Error()}
+	}
+
+	return transaction.Run(ctx, s.db, func(ctx context.Context) error {
+		tx := transaction.DB(ctx, s.db)
+		token, err := s.tokenService.consume(tx, params.Token, model.TokenPurposePasswordReset)
+		if err != nil {
+			return err
+		}
+
+		// 使用事务上下文查询，与令牌的消费在同一事务中
+		user, err := s.userService.FindUserByID(ctx, token.UserID)
+		if err != nil {
+			if err == ErrUserNotFound {
+				return ErrInvalidToken
+			}
+			return err
+		}
+
+		if err := user.ChangePassword(params.Password); err != nil {
+			return err
+		}
+		err = tx.Model(user).Updates(map[string]interface{}{
+			"password":      user.Password,
+			"token_version": gorm.Expr("token_version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// 作废其他尚未使用的重置链接
+		return s.tokenService.revoke(tx, user.ID, model.TokenPurposePasswordReset)
+	})
+}
+
+// resetURL 构造重置密码链接
+func (s *PasswordService) resetURL(token string) string {
+	return withToken(s.config.Security.PasswordResetURL, token)
+}
+
+// withToken 将令牌作为token参数附加到页面地址
+func withToken(pageURL, token string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return pageURL + "?token=" + url.QueryEscape(token)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// 单次查询允许的最大天数
+const maxStatisticsRangeDays = 366
+
+// 定义错误
+var (
+	ErrInvalidDateRange = errors.New("无效的日期范围")
+)
+
+// StatisticsService 统计数据服务
+type StatisticsService struct {
+	db *gorm.DB
+}
+
+// NewStatisticsService 创建新的统计数据服务实例
+func NewStatisticsService() *StatisticsService {
+	return &StatisticsService{
+		db: config.GetDB(),
+	}
+}
+
+// GetUserStatistics 获取日期范围内（含首尾）的每日用户统计
+func (s *StatisticsService) GetUserStatistics(ctx context.Context, start, end time.Time) ([]model.UserStatistic, error) {
+	if end.Before(start) || end.Sub(start) > maxStatisticsRangeDays*24*time.Hour {
+		return nil, ErrInvalidDateRange
+	}
+
+	stats := []model.UserStatistic{}
+	err := s.db.WithContext(ctx).
+		Where("date >= ? AND date <= ?", start, end).
+		Order("date ASC").
+		Find(&stats).Error
+	return stats, err
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// 恢复码数量
+const recoveryCodeCount = 10
+
+// 定义错误
+var (
+	ErrTwoFactorEnabled    = errors.New("两步验证已启用")
+	ErrTwoFactorNotEnabled = errors.New("两步验证未启用")
+	ErrTwoFactorNotSetup   = errors.New("请先获取两步验证密钥")
+	ErrInvalidTwoFactor    = errors.New("验证码错误")
+)
+
+// TwoFactorSetup 两步验证密钥
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth链接，可生成二维码供验证器应用扫描
+}
+
+// TwoFactorCodeParams 验证码参数
+type TwoFactorCodeParams struct {
+	Code string `json:"code" validate:"required,max=20"`
+}
+
+// DisableTwoFactorParams 关闭两步验证参数，需要同时提供密码和验证码
+type DisableTwoFactorParams struct {
+	Password string `json:"password" validate:"required,max=72"`
+	Code     string `json:"code" validate:"required,max=20"`
+}
+
+// TwoFactorService 两步验证服务
+type TwoFactorService struct {
+	config       *config.Config
+	db           *gorm.DB
+	userService  *UserService
+	auditService *AuditService
+}
+
+// NewTwoFactorService 创建新的两步验证服务实例
+func NewTwoFactorService() *TwoFactorService {
+	return &TwoFactorService{
+		config:       config.Load(),
+		db:           config.GetDB(),
+		userService:  DefaultUserService(),
+		auditService: NewAuditService(),
+	}
+}
+
+// Setup 生成新的TOTP密钥，确认前两步验证不会生效，重复调用会替换未确认的密钥
+func (s *TwoFactorService) Setup(ctx context.Context, user *model.User) (*TwoFactorSetup, error) {
+	if user.IsTwoFactorEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := config.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", user.ID).
+		UpdateColumns(map[string]interface{}{
+			"totp_secret":    secret,
+			"totp_last_step": 0,
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret: secret,
+		URI:    config.TOTPURI(secret, user.Email, s.config.App.Name),
+	}, nil
+}
+
+// Confirm 使用验证码确认启用两步验证，返回一次性恢复码明文
+func (s *TwoFactorService) Confirm(ctx context.Context, user *model.User, code string, client ClientInfo) ([]string, error) {
+	current, err := s.findUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current.IsTwoFactorEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	if current.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetup
+	}
+
+	ok, err := s.verifyTOTP(ctx, current, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactor
+	}
+
+	var codes []string
+	err = transaction.Run(ctx, s.db, func(ctx context.Context) error {
+		tx := transaction.DB(ctx, s.db)
+		if err := tx.Model(current).UpdateColumn("two_factor_enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		codes, err = s.replaceRecoveryCodes(tx, current.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(ctx, AuditEntry{
+		Action: model.AuditTwoFactorOn,
+		UserID: current.ID,
+		Client: client,
+	})
+	return codes, nil
+}
+
+// Disable 校验密码和验证码后关闭两步验证，恢复码一并删除
+// 密码或验证码错误与登录失败一样计入账号的连续失败次数，账号锁定期间不能关闭
+func (s *TwoFactorService) Disable(ctx context.Context, user *model.User, params DisableTwoFactorParams, client ClientInfo) error {
+	current, err := s.findUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if !current.IsTwoFactorEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+	if current.IsLocked() {
+		return &AccountLockedError{RetryAfter: time.Until(*current.LockedUntil)}
+	}
+	if !current.CheckPassword(params.Password) {
+		return s.verifyFailed(ctx, current, client, ErrInvalidCredentials)
+	}
+
+	ok, err := s.VerifyCode(ctx, current, params.Code, client)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return s.verifyFailed(ctx, current, client, ErrInvalidTwoFactor)
+	}
+
+	err = transaction.Run(ctx, s.db, func(ctx context.Context) error {
+		tx := transaction.DB(ctx, s.db)
+		err := tx.Model(current).UpdateColumns(map[string]interface{}{
+			"totp_secret":           "",
+			"totp_last_step":        0,
+			"two_factor_enabled_at": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", current.ID).Delete(&model.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, AuditEntry{
+		Action: model.AuditTwoFactorOff,
+		UserID: current.ID,
+		Client: client,
+	})
+	return nil
+}
+
+// RegenerateRecoveryCodes 作废所有恢复码并重新生成
+// 验证码错误计入账号的连续失败次数，账号锁定期间不能重新生成
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, user *model.User, code string, client ClientInfo) ([]string, error) {
+	current, err := s.findUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !current.IsTwoFactorEnabled() {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if current.IsLocked() {
+		return nil, &AccountLockedError{RetryAfter: time.Until(*current.LockedUntil)}
+	}
+
+	ok, err := s.verifyTOTP(ctx, current, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, s.verifyFailed(ctx, current, client, ErrInvalidTwoFactor)
+	}
+
+	var codes []string
+	err = transaction.Run(ctx, s.db, func(ctx context.Context) error {
+		tx := transaction.DB(ctx, s.db)
+		codes, err = s.replaceRecoveryCodes(tx, current.ID)
+		return err
+	})
+	return codes, err
+}
+
+// VerifyCode 校验TOTP验证码或恢复码，user需包含TOTPSecret
+// 6位数字按TOTP验证码处理，其他按恢复码处理，恢复码校验成功后即失效
+func (s *TwoFactorService) VerifyCode(ctx context.Context, user *model.User, code string, client ClientInfo) (bool, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if isTOTPCode(code) {
+		return s.verifyTOTP(ctx, user, code)
+	}
+
+	normalized := strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	result := s.db.WithContext(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashToken(normalized)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	s.auditService.Record(ctx, AuditEntry{
+		Action: model.AuditRecoveryUsed,
+		UserID: user.ID,
+		Client: client,
+	})
+	return true, nil
+}
+
+// verifyFailed 记录已登录用户敏感操作的验证失败，计入账号的连续失败次数
+// 持有会话令牌的攻击者因此无法无限尝试验证码，达到上限时返回AccountLockedError，否则返回err
+func (s *TwoFactorService) verifyFailed(ctx context.Context, user *model.User, client ClientInfo, err error) error {
+	s.auditService.Record(ctx, AuditEntry{
+		Action:  model.AuditTwoFactorFailed,
+		UserID:  user.ID,
+		Client:  client,
+		Details: map[string]interface{}{"reason": err.Error()},
+	})
+
+	lockedUntil, recordErr := s.userService.RecordLoginFailure(ctx, user.ID)
+	if recordErr != nil {
+		return recordErr
+	}
+	if lockedUntil == nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, AuditEntry{
+		Action:  model.AuditLoginLocked,
+		UserID:  user.ID,
+		Client:  client,
+		Details: map[string]interface{}{"locked_until": lockedUntil},
+	})
+	return &AccountLockedError{RetryAfter: time.Until(*lockedUntil)}
+}
+
+// verifyTOTP 校验TOTP验证码，同一时间步的验证码只能使用一次
+func (s *TwoFactorService) verifyTOTP(ctx context.Context, user *model.User, code string) (bool, error) {
+	step, ok := config.ValidateTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	// 只有时间步大于上次使用的时间步才能成功，并发请求中只有一个会成功
+	result := s.db.WithContext(ctx).Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		UpdateColumn("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// replaceRecoveryCodes 删除旧恢复码并生成新的恢复码，返回明文
+func (s *TwoFactorService) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]model.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		plain, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = plain[:5] + "-" + plain[5:]
+		records[i] = model.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(plain),
+		}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// findUser 从数据库读取包含TOTP密钥的用户
+func (s *TwoFactorService) findUser(ctx context.Context, id uint) (*model.User, error) {
+	var user model.User
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// generateRecoveryCode 生成10位小写Base32恢复码
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+	return strings.ToLower(encoded[:10]), nil
+}
+
+// isTOTPCode 判断是否为6位数字验证码
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// 定义错误
+var (
+	ErrUploadNotFound    = errors.New("上传会话不存在或已过期")
+	ErrUploadBusy        = errors.New("该上传会话正在接收其他分片")
+	ErrUploadIncomplete  = errors.New("文件尚未上传完成")
+	ErrTooManyUploads    = errors.New("未完成的上传数量超过限制")
+	ErrChecksumMismatch  = errors.New("文件校验失败")
+	ErrUnsupportedDigest = errors.New("不支持的校验算法")
+	ErrUploadUnavailable = errors.New("上传会话不在当前节点，请稍后重试")
+)
+
+// UploadOffsetError 分片的偏移量与已接收的内容长度不一致
+type UploadOffsetError struct {
+	Offset int64 // 服务端已接收的内容长度，客户端应从此处继续上传
+}
+
+func (e *UploadOffsetError) Error() string {
+	return "分片偏移量与已上传的长度不一致"
+}
+
+// CreateUploadParams 创建分片上传的参数
+type CreateUploadParams struct {
+	Name     string `json:"name" validate:"required,max=255"`
+	Size     int64  `json:"size" validate:"required,gt=0"`
+	Checksum string `json:"checksum" validate:"omitempty,len=64,hexadecimal"` // 整个文件的SHA-256哈希
+}
+
+// uploadLocks 正在接收分片或完成上传的会话，同一会话的请求不能并发执行
+// 临时文件保存在本机，会话只能由创建它的节点处理，进程内的锁即可保证互斥
+// 因此多节点部署时负载均衡需要按客户端做会话保持，共享UPLOAD_TEMP_PATH目录不能代替会话保持，进程内的锁无法阻止两个节点同时写入
+var uploadLocks = struct {
+	sync.Mutex
+	busy map[string]bool
+}{busy: make(map[string]bool)}
+
+// UploadSessionService 分片上传服务
+// 客户端创建会话后按顺序上传分片，中断后可查询已接收的长度并从该位置继续，全部上传后完成会话得到文件记录
+type UploadSessionService struct {
+	config      *config.Config
+	db          *gorm.DB
+	fileService *FileService
+}
+
+// NewUploadSessionService 创建新的分片上传服务实例
+func NewUploadSessionService() *UploadSessionService {
+	return &UploadSessionService{
+		config:      config.Load(),
+		db:          config.GetDB(),
+		fileService: NewFileService(),
+	}
+}
+
+// Create 创建分片上传会话
+func (s *UploadSessionService) Create(ctx context.Context, user *model.User, params CreateUploadParams) (*model.UploadSession, error) {
+	if params.Size > s.config.Upload.ResumableMaxSize {
+		return nil, ErrFileTooLarge
+	}
+
+	var active int64
+	err := s.db.WithContext(ctx).Model(&model.UploadSession{}).
+		Where("user_id = ? AND expires_at > ?", user.ID, time.Now()).
+		Count(&active).Error
+	if err != nil {
+		return nil, err
+	}
+	if active >= int64(s.config.Upload.MaxSessions) {
+		return nil, ErrTooManyUploads
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	session := &model.UploadSession{
+		ID:        hex.EncodeToString(id),
+		UserID:    user.ID,
+		Name:      sanitizeFilename(params.Name),
+		Size:      params.Size,
+		Checksum:  strings.ToLower(params.Checksum),
+		ExpiresAt: time.Now().Add(s.config.Upload.SessionTTL),
+	}
+	// 先写入会话再创建临时文件，清理任务不会误删刚创建的文件
+	if err := s.db.WithContext(ctx).Create(session).Error; err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(s.tempPath(session), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		s.db.WithContext(ctx).Delete(session)
+		return nil, err
+	}
+	f.Close()
+	return session, nil
+}
+
+// List 获取用户未完成的分片上传
+func (s *UploadSessionService) List(ctx context.Context, userID uint) ([]model.UploadSession, error) {
+	sessions := []model.UploadSession{}
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Get 获取属于指定用户且未过期的会话
+func (s *UploadSessionService) Get(ctx context.Context, userID uint, id string) (*model.UploadSession, error) {
+	var session model.UploadSession
+	err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	if session.IsExpired() {
+		return nil, ErrUploadNotFound
+	}
+	return &session, nil
+}
+
+// WriteChunk 从offset处写入一个分片，offset必须等于已接收的长度
+// digest为分片的SHA-256哈希，提供时校验失败的分片会被丢弃；未提供时连接中断前收到的内容会被保留
+func (s *UploadSessionService) WriteChunk(ctx context.Context, userID uint, id string, offset int64, r io.Reader, digest []byte) (*model.UploadSession, error) {
+	unlock, err := lockUpload(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	session, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != session.Offset {
+		return nil, &UploadOffsetError{Offset: session.Offset}
+	}
+
+	f, err := s.openChunkFile(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// 多读一个字节用于判断是否超过声明的文件大小
+	hash := sha256.New()
+	written, copyErr := io.Copy(io.MultiWriter(f, hash), io.LimitReader(r, session.Size-offset+1))
+	end := offset + written
+	switch {
+	case end > session.Size:
+		copyErr = ErrFileTooLarge
+		end = offset
+	case digest != nil && copyErr == nil && !bytes.Equal(hash.Sum(nil), digest):
+		copyErr = ErrChecksumMismatch
+		end = offset
+	case digest != nil && copyErr != nil:
+		end = offset
+	}
+	// 丢弃未被接受的内容
+	if err := f.Truncate(end); err != nil {
+		return nil, err
+	}
+
+	if end > offset {
+		expiresAt := time.Now().Add(s.config.Upload.SessionTTL)
+		result := s.db.WithContext(ctx).Model(session).
+			Where("upload_offset = ?", offset).
+			UpdateColumns(map[string]interface{}{"upload_offset": end, "expires_at": expiresAt, "updated_at": time.Now()})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		// 会话已被清理任务删除
+		if result.RowsAffected == 0 {
+			return nil, ErrUploadNotFound
+		}
+		session.Offset = end
+		session.ExpiresAt = expiresAt
+	}
+	if copyErr != nil {
+		if errors.Is(copyErr, ErrFileTooLarge) || errors.Is(copyErr, ErrChecksumMismatch) {
+			return nil, copyErr
+		}
+		return nil, ErrInvalidUpload
+	}
+	return session, nil
+}
+
+// Complete 完成分片上传，校验文件类型和哈希后保存为文件记录，会话随后删除
+func (s *UploadSessionService) Complete(ctx context.Context, userID uint, id string) (*model.File, error) {
+	unlock, err := lockUpload(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	session, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !session.IsComplete() {
+		return nil, ErrUploadIncomplete
+	}
+
+	p, err := s.inspect(session)
+	if errors.Is(err, ErrFileTypeNotAllowed) || errors.Is(err, ErrChecksumMismatch) {
+		// 内容不可能再通过校验，直接删除会话
+		s.remove(ctx, session)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := s.fileService.create(ctx, userID, []*pendingFile{p})
+	if err != nil {
+		return nil, err
+	}
+	s.remove(ctx, session)
+	return &files[0], nil
+}
+
+// Abort 取消分片上传并删除已接收的内容
+func (s *UploadSessionService) Abort(ctx context.Context, userID uint, id string) error {
+	unlock, err := lockUpload(id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	session, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	return s.remove(ctx, session)
+}
+
+// openChunkFile 打开临时文件并定位到已接收内容的末尾
+// 临时文件不存在时请求可能被转发到了其他节点，不删除会话，返回可重试的错误；会话过期后由定时任务清理
+// 内容少于记录的长度说明本节点上的临时文件已损坏，会话无法继续，直接删除
+func (s *UploadSessionService) openChunkFile(ctx context.Context, session *model.UploadSession) (*os.File, error) {
+	f, err := os.OpenFile(s.tempPath(session), os.O_WRONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrUploadUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err == nil && info.Size() < session.Offset {
+		err = ErrUploadNotFound
+		s.remove(ctx, session)
+	}
+	if err == nil {
+		_, err = f.Seek(session.Offset, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// inspect 根据内容识别文件类型并计算哈希
+func (s *UploadSessionService) inspect(session *model.UploadSession) (*pendingFile, error) {
+	f, err := os.Open(s.tempPath(session))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrUploadUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	// 不信任客户端声明的Content-Type，只根据内容识别
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if !s.fileService.allowedType(mimeType) {
+		return nil, ErrFileTypeNotAllowed
+	}
+
+	hash := sha256.New()
+	hash.Write(head[:n])
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if session.Checksum != "" && session.Checksum != checksum {
+		return nil, ErrChecksumMismatch
+	}
+
+	return &pendingFile{
+		tmpPath:  f.Name(),
+		name:     session.Name,
+		mimeType: mimeType,
+		size:     session.Size,
+		checksum: checksum,
+	}, nil
+}
+
+// remove 删除会话及其临时文件
+func (s *UploadSessionService) remove(ctx context.Context, session *model.UploadSession) error {
+	if err := s.db.WithContext(ctx).Delete(session).Error; err != nil {
+		return err
+	}
+	if err := os.Remove(s.tempPath(session)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// tempPath 返回会话临时文件的路径
+func (s *UploadSessionService) tempPath(session *model.UploadSession) string {
+	return filepath.Join(s.config.Upload.TempPath, session.FileName())
+}
+
+// lockUpload 标记会话正在处理，已在处理时返回ErrUploadBusy
+func lockUpload(id string) (func(), error) {
+	uploadLocks.Lock()
+	defer uploadLocks.Unlock()
+	if uploadLocks.busy[id] {
+		return nil, ErrUploadBusy
+	}
+	uploadLocks.busy[id] = true
+	return func() {
+		uploadLocks.Lock()
+		delete(uploadLocks.busy, id)
+		uploadLocks.Unlock()
+	}, nil
+}
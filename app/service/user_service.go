@@ -1,13 +1,18 @@
 package service
 
 import (
+	"context"
 	"errors"
-	"strconv"
+	"fmt"
+	"maps"
+	"time"
 
+	"github.com/NextEraAbyss/fiber-template/app/cache"
+	"github.com/NextEraAbyss/fiber-template/app/filter"
 	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/pagination"
+	"github.com/NextEraAbyss/fiber-template/app/repository"
 	"github.com/NextEraAbyss/fiber-template/config"
-	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 // 定义错误
@@ -15,85 +20,203 @@ var (
 	ErrUserNotFound = errors.New("用户不存在")
 )
 
-// UserQueryParams 用户查询参数
-type UserQueryParams struct {
-	Page     int    `json:"page"`
-	PageSize int    `json:"page_size"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-	IsActive string `json:"is_active"`
+// 用户缓存的过期时间
+const userCacheTTL = 10 * time.Minute
+
+// UserLockout 用户登录锁定状态
+type UserLockout struct {
+	ID               uint       `json:"id"`
+	Username         string     `json:"username"`
+	Email            string     `json:"email"`
+	FailedLoginCount int        `json:"failed_login_count"`
+	LockoutCount     int        `json:"lockout_count"`
+	LockedUntil      *time.Time `json:"locked_until"`
 }
 
 // UserService 用户服务
 type UserService struct {
-	db *gorm.DB
+	config *config.Config
+	users  repository.UserRepository
+	cache  cache.Store
+	audit  AuditRecorder
 }
 
-// NewUserService 创建新的用户服务实例
-func NewUserService() *UserService {
+// NewUserService 使用指定的依赖创建用户服务实例
+func NewUserService(cfg *config.Config, users repository.UserRepository, store cache.Store, audit AuditRecorder) *UserService {
 	return &UserService{
-		db: config.GetDB(),
+		config: cfg,
+		users:  users,
+		cache:  store,
+		audit:  audit,
 	}
 }
 
-// GetUsers 获取用户列表
-func (s *UserService) GetUsers(params UserQueryParams) ([]model.User, fiber.Map, error) {
-	// 构建查询
-	query := s.db.Model(&model.User{})
+// DefaultUserService 使用全局配置、数据库和缓存创建用户服务实例
+func DefaultUserService() *UserService {
+	return NewUserService(config.Load(), repository.NewUserRepository(config.GetDB()), config.GetCache(), NewAuditService())
+}
+
+// UserListOptions 用户列表的分页和排序选项
+var UserListOptions = pagination.Options{
+	Sorts:       []string{"username", "created_at"},
+	DefaultSort: "id",
+}
+
+// UserFilters 用户列表允许筛选的字段
+var UserFilters = filter.Fields{
+	"username":          {Type: filter.String},
+	"email":             {Type: filter.String},
+	"role":              {Type: filter.String, Values: []string{model.RoleAdmin, model.RoleUser, model.RoleGuest}},
+	"is_active":         {Type: filter.Bool},
+	"created_at":        {Type: filter.Time},
+	"email_verified_at": {Type: filter.Time, Nullable: true},
+}
+
+// PublicUserFilters 非管理员允许筛选的字段，不能按私有字段筛选，否则可以据此推断出私有字段的值
+var PublicUserFilters = func() filter.Fields {
+	fields := maps.Clone(UserFilters)
+	delete(fields, "email_verified_at")
+	return fields
+}()
 
-	// 添加查询条件
-	if params.Username != "" {
-		query = query.Where("username LIKE ?", "%"+params.Username+"%")
+// GetUsers 获取用户列表，scopes用于添加筛选条件和选择查询的列
+func (s *UserService) GetUsers(ctx context.Context, page *pagination.Request, scopes ...repository.Scope) ([]model.User, *pagination.Page, error) {
+	return s.users.Paginate(ctx, page, scopes...)
+}
+
+// GetUserByID 通过ID获取用户，结果会被缓存，用于展示
+// 缓存中的用户不包含密码、令牌版本等不参与JSON序列化的字段，并可能在其他节点修改后短暂过期，
+// 认证等需要最新状态的场景使用FindUserByID
+func (s *UserService) GetUserByID(ctx context.Context, id uint) (*model.User, error) {
+	var user model.User
+	err := s.cache.Remember(ctx, userCacheKey(id), userCacheTTL, &user, func() (interface{}, error) {
+		return s.FindUserByID(ctx, id)
+	}, userCacheTags(id)...)
+	if err != nil {
+		return nil, err
 	}
-	if params.Email != "" {
-		query = query.Where("email LIKE ?", "%"+params.Email+"%")
+	return &user, nil
+}
+
+// FindUserByID 直接从数据库通过ID获取用户，上下文中有事务时在事务中查询
+func (s *UserService) FindUserByID(ctx context.Context, id uint) (*model.User, error) {
+	return userResult(s.users.FindByID(ctx, id))
+}
+
+// GetUserByLogin 通过用户名或邮箱获取用户
+func (s *UserService) GetUserByLogin(ctx context.Context, login string) (*model.User, error) {
+	return userResult(s.users.FindByLogin(ctx, login))
+}
+
+// GetUserByEmail 通过邮箱获取用户
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	return userResult(s.users.FindByEmail(ctx, email))
+}
+
+// RecordLoginFailure 记录一次密码错误，达到上限时锁定账号并返回锁定截止时间
+// 每次锁定的时长是上一次的两倍，直到LoginLockoutMaxDuration
+func (s *UserService) RecordLoginFailure(ctx context.Context, id uint) (*time.Time, error) {
+	var lockedUntil *time.Time
+	err := s.users.Transaction(ctx, func(ctx context.Context) error {
+		user, err := s.users.FindForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		failures := user.FailedLoginCount + 1
+		if failures < s.config.Security.LoginMaxAttempts {
+			return s.users.Update(ctx, user, map[string]interface{}{"failed_login_count": failures})
+		}
+
+		lockouts := user.LockoutCount + 1
+		until := time.Now().Add(s.lockoutDuration(lockouts))
+		lockedUntil = &until
+		return s.users.Update(ctx, user, map[string]interface{}{
+			"failed_login_count": 0,
+			"lockout_count":      lockouts,
+			"locked_until":       until,
+		})
+	})
+	return lockedUntil, err
+}
+
+// ResetLoginFailures 登录成功后清除失败次数和锁定状态
+func (s *UserService) ResetLoginFailures(ctx context.Context, user *model.User) error {
+	if user.FailedLoginCount == 0 && user.LockoutCount == 0 && user.LockedUntil == nil {
+		return nil
 	}
-	if params.Role != "" {
-		query = query.Where("role = ?", params.Role)
+	return s.users.Update(ctx, user, map[string]interface{}{
+		"failed_login_count": 0,
+		"lockout_count":      0,
+		"locked_until":       nil,
+	})
+}
+
+// GetLockedUsers 获取当前处于锁定期或有登录失败记录的用户
+func (s *UserService) GetLockedUsers(ctx context.Context) ([]UserLockout, error) {
+	users, err := s.users.FindLockedOut(ctx, time.Now())
+	if err != nil {
+		return nil, err
 	}
-	if params.IsActive != "" {
-		// 将字符串转换为整数
-		active, err := strconv.Atoi(params.IsActive)
-		if err == nil {
-			query = query.Where("is_active = ?", active)
+	lockouts := make([]UserLockout, len(users))
+	for i, user := range users {
+		lockouts[i] = UserLockout{
+			ID:               user.ID,
+			Username:         user.Username,
+			Email:            user.Email,
+			FailedLoginCount: user.FailedLoginCount,
+			LockoutCount:     user.LockoutCount,
+			LockedUntil:      user.LockedUntil,
 		}
 	}
+	return lockouts, nil
+}
 
-	// 计算总数
-	var total int64
-	if err := query.Count(&total).Error; err != nil {
-		return nil, nil, err
+// UnlockUser 由管理员解除用户的登录锁定
+func (s *UserService) UnlockUser(ctx context.Context, id uint, actor *model.User, client ClientInfo) error {
+	user, err := s.FindUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.ResetLoginFailures(ctx, user); err != nil {
+		return err
 	}
 
-	// 计算总页数
-	totalPages := (total + int64(params.PageSize) - 1) / int64(params.PageSize)
+	s.audit.Record(ctx, AuditEntry{
+		Action:  model.AuditAccountUnlock,
+		UserID:  user.ID,
+		ActorID: actor.ID,
+		Client:  client,
+	})
+	return nil
+}
 
-	// 获取分页数据
-	var users []model.User
-	if err := query.Offset((params.Page - 1) * params.PageSize).Limit(params.PageSize).Find(&users).Error; err != nil {
-		return nil, nil, err
+// lockoutDuration 返回第n次锁定的时长
+func (s *UserService) lockoutDuration(n int) time.Duration {
+	d := s.config.Security.LoginLockoutDuration
+	for i := 1; i < n; i++ {
+		d *= 2
+		if d >= s.config.Security.LoginLockoutMaxDuration {
+			return s.config.Security.LoginLockoutMaxDuration
+		}
 	}
+	return d
+}
 
-	// 构建分页信息
-	pagination := fiber.Map{
-		"total":       total,
-		"page":        params.Page,
-		"page_size":   params.PageSize,
-		"total_pages": totalPages,
+// userResult 将仓库返回的ErrNotFound转换为ErrUserNotFound
+func userResult(user *model.User, err error) (*model.User, error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotFound
 	}
+	return user, err
+}
 
-	return users, pagination, nil
+// userCacheKey 返回用户缓存键
+func userCacheKey(id uint) string {
+	return fmt.Sprintf("users:%d", id)
 }
 
-// GetUserByID 通过ID获取用户
-func (s *UserService) GetUserByID(id uint) (*model.User, error) {
-	var user model.User
-	if err := s.db.First(&user, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, ErrUserNotFound
-		}
-		return nil, err
-	}
-	return &user, nil
+// userCacheTags 返回用户缓存的标签，"users"用于失效所有用户缓存
+func userCacheTags(id uint) []string {
+	return []string{"users", fmt.Sprintf("user:%d", id)}
 }
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/cache"
+	"github.com/NextEraAbyss/fiber-template/app/filter"
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/pagination"
+	"github.com/NextEraAbyss/fiber-template/app/repository"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 打开以测试名称命名的内存数据库并迁移指定模型
+func openTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger:         logger.Discard,
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("迁移数据库失败: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// auditLog 记录审计日志的测试实现
+type auditLog []AuditEntry
+
+func (l *auditLog) Record(ctx context.Context, entry AuditEntry) {
+	*l = append(*l, entry)
+}
+
+// setupUsers 使用内存数据库和内存缓存创建UserService
+func setupUsers(t *testing.T) (*UserService, repository.UserRepository, *auditLog) {
+	t.Helper()
+
+	users := repository.NewUserRepository(openTestDB(t, &model.User{}))
+	cfg := &config.Config{}
+	cfg.Security.LoginMaxAttempts = 3
+	cfg.Security.LoginLockoutDuration = time.Minute
+	cfg.Security.LoginLockoutMaxDuration = 3 * time.Minute
+	audit := &auditLog{}
+	store := cache.New(cache.NewMemoryDriver(100), "test:", time.Minute, false)
+	return NewUserService(cfg, users, store, audit), users, audit
+}
+
+// createUsers 按顺序创建用户，密码使用Pass-word1的低成本哈希，避免每个用户都计算bcrypt
+func createUsers(t *testing.T, users repository.UserRepository, roles ...string) {
+	t.Helper()
+	for i, role := range roles {
+		user := &model.User{
+			Username: fmt.Sprintf("user%d", i+1),
+			Email:    fmt.Sprintf("user%d@example.com", i+1),
+			Password: "$2a$04$tsyC/LNo0fFUtjFwwC3Wv.2TbNkoUjbQJhaEPaOIuu6oOBl0pITmO",
+			Role:     role,
+		}
+		if err := users.Create(context.Background(), user); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetUsersPaginatesAndFilters(t *testing.T) {
+	s, users, _ := setupUsers(t)
+	createUsers(t, users, model.RoleUser, model.RoleAdmin, model.RoleUser, model.RoleUser, model.RoleAdmin)
+
+	// 与控制器相同，从查询参数解析分页和筛选条件
+	list := func(query string) ([]model.User, *pagination.Page) {
+		t.Helper()
+		var items []model.User
+		var page *pagination.Page
+		app := fiber.New()
+		app.Get("/users", func(ctx *fiber.Ctx) error {
+			req, err := pagination.Parse(ctx, UserListOptions)
+			if err != nil {
+				return err
+			}
+			f, err := filter.Parse(ctx, UserFilters)
+			if err != nil {
+				return err
+			}
+			items, page, err = s.GetUsers(ctx.UserContext(), req, f.Scope)
+			return err
+		})
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/users?"+query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("GET /users?%s: status = %d", query, resp.StatusCode)
+		}
+		return items, page
+	}
+	usernames := func(items []model.User) []string {
+		names := make([]string, len(items))
+		for i, u := range items {
+			names[i] = u.Username
+		}
+		return names
+	}
+
+	items, page := list("page_size=2&filter[role]=user")
+	if got := fmt.Sprint(usernames(items)); got != "[user1 user3]" || !page.HasMore || page.NextCursor == "" {
+		t.Fatalf("first page = %s, %+v", got, page)
+	}
+
+	items, page = list("page_size=2&filter[role]=user&cursor=" + page.NextCursor)
+	if got := fmt.Sprint(usernames(items)); got != "[user4]" || page.HasMore {
+		t.Fatalf("second page = %s, %+v", got, page)
+	}
+
+	items, _ = list("sort=-username&filter[role]=admin")
+	if got := fmt.Sprint(usernames(items)); got != "[user5 user2]" {
+		t.Fatalf("admins = %s", got)
+	}
+}
+
+func TestRecordLoginFailureLocksAccount(t *testing.T) {
+	s, users, _ := setupUsers(t)
+	createUsers(t, users, model.RoleUser)
+	ctx := context.Background()
+
+	// 每次锁定的时长翻倍，直到LoginLockoutMaxDuration
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		for i := 1; i < s.config.Security.LoginMaxAttempts; i++ {
+			if until, err := s.RecordLoginFailure(ctx, 1); err != nil || until != nil {
+				t.Fatalf("failure %d: until = %v, err = %v", i, until, err)
+			}
+		}
+		until, err := s.RecordLoginFailure(ctx, 1)
+		if err != nil || until == nil {
+			t.Fatalf("lockout: until = %v, err = %v", until, err)
+		}
+		if d := time.Until(*until); d < want-time.Second || d > want {
+			t.Fatalf("lockout duration = %v, want %v", d, want)
+		}
+	}
+
+	user, err := s.FindUserByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.IsLocked() || user.LockoutCount != 3 || user.FailedLoginCount != 0 {
+		t.Fatalf("user = locked %v, lockouts %d, failures %d", user.IsLocked(), user.LockoutCount, user.FailedLoginCount)
+	}
+
+	if err := s.ResetLoginFailures(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ = s.FindUserByID(ctx, 1); user.IsLocked() || user.LockoutCount != 0 {
+		t.Fatalf("after reset: locked %v, lockouts %d", user.IsLocked(), user.LockoutCount)
+	}
+}
+
+func TestUnlockUserRecordsAudit(t *testing.T) {
+	s, users, audit := setupUsers(t)
+	createUsers(t, users, model.RoleUser, model.RoleAdmin)
+	ctx := context.Background()
+
+	for i := 0; i < s.config.Security.LoginMaxAttempts; i++ {
+		if _, err := s.RecordLoginFailure(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	admin, err := s.FindUserByID(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UnlockUser(ctx, 1, admin, ClientInfo{IP: "127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.FindUserByID(ctx, 1); user.IsLocked() {
+		t.Fatal("user is still locked")
+	}
+	if len(*audit) != 1 || (*audit)[0].Action != model.AuditAccountUnlock || (*audit)[0].ActorID != 2 {
+		t.Fatalf("audit = %+v", *audit)
+	}
+
+	if err := s.UnlockUser(ctx, 99, admin, ClientInfo{}); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("err = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestUserTransaction(t *testing.T) {
+	s, users, _ := setupUsers(t)
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	// 使用事务上下文的查询能看到事务中尚未提交的用户，fn返回错误时回滚
+	err := users.Transaction(ctx, func(ctx context.Context) error {
+		user := &model.User{Username: "tx", Email: "tx@example.com", Password: "x"}
+		if err := users.Create(ctx, user); err != nil {
+			return err
+		}
+		if _, err := s.GetUserByLogin(ctx, "tx@example.com"); err != nil {
+			t.Errorf("GetUserByLogin in transaction: %v", err)
+		}
+		if _, err := users.FindForUpdate(ctx, user.ID); err != nil {
+			t.Errorf("FindForUpdate in transaction: %v", err)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("err = %v, want %v", err, errRollback)
+	}
+	if _, err := s.GetUserByLogin(ctx, "tx"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("after rollback: err = %v, want %v", err, ErrUserNotFound)
+	}
+
+	err = users.Transaction(ctx, func(ctx context.Context) error {
+		return users.Create(ctx, &model.User{Username: "committed", Email: "committed@example.com", Password: "x"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetUserByEmail(ctx, "Committed@Example.com"); err != nil {
+		t.Fatalf("after commit: %v", err)
+	}
+}
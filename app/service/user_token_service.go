@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// 定义错误
+var (
+	ErrInvalidToken = errors.New("链接无效或已过期")
+)
+
+// UserTokenService 一次性用户令牌服务
+type UserTokenService struct {
+	db *gorm.DB
+}
+
+// NewUserTokenService 创建新的用户令牌服务实例
+func NewUserTokenService() *UserTokenService {
+	return &UserTokenService{
+		db: config.GetDB(),
+	}
+}
+
+// Issue 为用户签发指定用途的令牌并返回明文，同一用途此前未使用的令牌会被作废
+func (s *UserTokenService) Issue(ctx context.Context, user *model.User, purpose, email string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	plain := base64.RawURLEncoding.EncodeToString(b)
+
+	err := transaction.Run(ctx, s.db, func(ctx context.Context) error {
+		tx := transaction.DB(ctx, s.db)
+		if err := s.revoke(tx, user.ID, purpose); err != nil {
+			return err
+		}
+		return tx.Create(&model.UserToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			TokenHash: hashToken(plain),
+			Email:     email,
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// consume 校验并使用令牌，令牌只能成功使用一次
+// 通过带条件的更新标记为已使用，并发请求中只有一个会成功
+func (s *UserTokenService) consume(tx *gorm.DB, plain, purpose string) (*model.UserToken, error) {
+	if plain == "" {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	hash := hashToken(plain)
+	result := tx.Model(&model.UserToken{}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	var token model.UserToken
+	if err := tx.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// revoke 删除用户指定用途的所有未使用令牌
+func (s *UserTokenService) revoke(tx *gorm.DB, userID uint, purpose string) error {
+	return tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Delete(&model.UserToken{}).Error
+}
+
+// hashToken 计算令牌的SHA-256哈希
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/NextEraAbyss/fiber-template/app/model"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"github.com/NextEraAbyss/fiber-template/config"
+	"gorm.io/gorm"
+)
+
+// 定义错误
+var (
+	ErrEmailAlreadyVerified = errors.New("邮箱已验证")
+	ErrEmailTaken           = errors.New("该邮箱已被使用")
+	ErrInvalidEmail         = errors.New("邮箱格式不正确")
+)
+
+// RateLimitError 操作过于频繁，需等待RetryAfter后重试
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return "操作过于频繁，请稍后再试"
+}
+
+// ChangeEmailParams 修改邮箱参数
+type ChangeEmailParams struct {
+	Email    string `json:"email" validate:"required,email,max=100"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+// VerifyEmailParams 验证邮箱参数
+type VerifyEmailParams struct {
+	Token string `json:"token" validate:"required,max=100"`
+}
+
+// VerificationService 邮箱验证服务
+// 修改邮箱时新邮箱保存在验证令牌中，验证通过后才会替换用户当前的邮箱
+type VerificationService struct {
+	config       *config.Config
+	db           *gorm.DB
+	tokenService *UserTokenService
+	mailService  *MailService
+}
+
+// NewVerificationService 创建新的邮箱验证服务实例
+func NewVerificationService() *VerificationService {
+	return &VerificationService{
+		config:       config.Load(),
+		db:           config.GetDB(),
+		tokenService: NewUserTokenService(),
+		mailService:  NewMailService(),
+	}
+}
+
+// SendVerification 向指定邮箱发送验证链接，邮件发送失败只记录日志
+func (s *VerificationService) SendVerification(ctx context.Context, user *model.User, email string) error {
+	ttl := s.config.Security.EmailVerifyTTL
+	token, err := s.tokenService.Issue(ctx, user, model.TokenPurposeVerifyEmail, email, ttl)
+	if err != nil {
+		return err
+	}
+
+	err = s.mailService.SendToAddress(ctx, user, email, MailVerifyEmail, map[string]interface{}{
+		"URL":            s.verifyURL(token),
+		"ExpiresMinutes": int(ttl.Minutes()),
+	})
+	if err != nil {
+		log.Printf("发送验证邮件失败: user=%d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// Resend 重新发送当前邮箱的验证邮件，两次发送之间至少间隔EmailVerifyResend
+func (s *VerificationService) Resend(ctx context.Context, user *model.User) error {
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	if err := s.checkInterval(ctx, user.ID); err != nil {
+		return err
+	}
+	return s.SendVerification(ctx, user, user.Email)
+}
+
+// checkInterval 检查距离上一次发送验证邮件是否已超过EmailVerifyResend，重新发送和修改邮箱共用该限制
+func (s *VerificationService) checkInterval(ctx context.Context, userID uint) error {
+	var last model.UserToken
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND purpose = ?", userID, model.TokenPurposeVerifyEmail).
+		Order("created_at DESC").
+		Take(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if wait := time.Until(last.CreatedAt.Add(s.config.Security.EmailVerifyResend)); wait > 0 {
+		return &RateLimitError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// ChangeEmail 校验密码后向新邮箱发送验证链接，验证通过后邮箱才会被修改
+// 与重新发送共用发送间隔限制，避免被用来向任意地址频繁发送邮件，同时通知原邮箱
+func (s *VerificationService) ChangeEmail(ctx context.Context, user *model.User, params ChangeEmailParams) error {
+	email, ok := config.SanitizeEmail(params.Email)
+	if !ok {
+		return ErrInvalidEmail
+	}
+
+	// 缓存中的用户不包含密码，需从数据库重新读取
+	var current model.User
+	if err := s.db.WithContext(ctx).First(&current, user.ID).Error; err != nil {
+		return err
+	}
+	if !current.CheckPassword(params.Password) {
+		return ErrInvalidCredentials
+	}
+	if email == current.Email {
+		if current.IsEmailVerified() {
+			return ErrEmailAlreadyVerified
+		}
+		return s.Resend(ctx, &current)
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&model.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrEmailTaken
+	}
+	if err := s.checkInterval(ctx, current.ID); err != nil {
+		return err
+	}
+
+	if err := s.SendVerification(ctx, &current, email); err != nil {
+		return err
+	}
+	err := s.mailService.SendToUser(ctx, &current, MailEmailChange, map[string]interface{}{
+		"NewEmail": email,
+	})
+	if err != nil {
+		log.Printf("发送邮箱修改通知失败: user=%d: %v", current.ID, err)
+	}
+	return nil
+}
+
+// Verify 使用验证令牌确认邮箱，令牌中的邮箱与当前邮箱不同时替换为新邮箱
+func (s *VerificationService) Verify(ctx context.Context, params VerifyEmailParams) (*model.User, error) {
+	var user model.User
+	var firstVerification bool
+	err := transaction.Run(ctx, s.db, func(ctx context.Context) error {
+		tx := transaction.DB(ctx, s.db)
+		token, err := s.tokenService.consume(tx, params.Token, model.TokenPurposeVerifyEmail)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidToken
+			}
+			return err
+		}
+
+		firstVerification = !user.IsEmailVerified()
+		now := time.Now()
+		err = tx.Model(&user).Updates(map[string]interface{}{
+			"email":             token.Email,
+			"email_verified_at": now,
+		}).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrEmailTaken
+		}
+		if err != nil {
+			return err
+		}
+		user.Email = token.Email
+		user.EmailVerifiedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if firstVerification {
+		if err := s.mailService.SendToUser(ctx, &user, MailWelcome, nil); err != nil {
+			log.Printf("发送欢迎邮件失败: user=%d: %v", user.ID, err)
+		}
+	}
+	return &user, nil
+}
+
+// verifyURL 构造邮箱验证链接
+func (s *VerificationService) verifyURL(token string) string {
+	return withToken(s.config.Security.EmailVerifyURL, token)
+}
@@ -0,0 +1,477 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3分片上传的限制
+const (
+	s3MinPartSize = 5 << 20 // 除最后一个分片外，每个分片不能小于5MB
+	s3MaxParts    = 10000
+)
+
+// SigV4签名使用的常量
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3EmptyHash       = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3MaxPresignTTL   = 7 * 24 * time.Hour
+)
+
+// S3Config S3兼容存储配置
+type S3Config struct {
+	Endpoint  string // 服务地址，为空时使用AWS区域地址，MinIO等需指定
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool  // 使用 endpoint/bucket/key 形式的地址，MinIO等通常需要开启
+	PartSize  int64 // 分片大小，超过该大小的文件使用分片上传
+}
+
+// S3 S3兼容对象存储，使用AWS签名V4访问REST API
+type S3 struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time // 签名使用的时间，测试时替换
+}
+
+// NewS3 创建S3兼容对象存储，client为空时使用不设超时的默认客户端，请求超时由上下文控制
+func NewS3(config S3Config, client *http.Client) (*S3, error) {
+	if config.Bucket == "" || config.Region == "" {
+		return nil, fmt.Errorf("S3存储缺少Bucket或Region配置")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的S3地址: %s", config.Endpoint)
+	}
+	if config.PartSize < s3MinPartSize {
+		config.PartSize = s3MinPartSize
+	}
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &S3{
+		config:   config,
+		endpoint: endpoint,
+		client:   client,
+		now:      time.Now,
+	}, nil
+}
+
+// Put 写入文件，超过分片大小或长度未知且超过一个分片的文件使用分片上传
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	if size >= 0 && size <= s.config.PartSize {
+		return s.putObject(ctx, key, r, size, contentType)
+	}
+
+	buf := make([]byte, s.config.PartSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return s.putObject(ctx, key, bytes.NewReader(buf[:n]), int64(n), contentType)
+	}
+	if err != nil {
+		return err
+	}
+	return s.multipartUpload(ctx, key, buf, r, contentType)
+}
+
+// Get 读取文件
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// GetRange 以Range请求读取文件的一部分
+func (s *S3) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	header := http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)}}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, header, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete 删除文件
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Stat 获取文件元数据
+func (s *S3) Stat(ctx context.Context, key string) (*Object, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &Object{
+		Key:         key,
+		Size:        size,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+		ModTime:     modTime,
+	}, nil
+}
+
+// List 列出键以prefix开头的所有文件，自动处理分页
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		var result struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				ETag         string    `xml:"ETag"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := s.doXML(ctx, http.MethodGet, "", query, nil, &result); err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Contents {
+			objects = append(objects, Object{
+				Key:     c.Key,
+				Size:    c.Size,
+				ETag:    strings.Trim(c.ETag, `"`),
+				ModTime: c.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// SignedURL 生成预签名的GET地址，有效期最长7天
+func (s *S3) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	if ttl <= 0 || ttl > s3MaxPresignTTL {
+		return "", fmt.Errorf("预签名地址的有效期需在0到%v之间", s3MaxPresignTTL)
+	}
+
+	now := s.now().UTC()
+	u := s.objectURL(key)
+	query := url.Values{
+		"X-Amz-Algorithm":     {s3Algorithm},
+		"X-Amz-Credential":    {s.config.AccessKey + "/" + s.scope(now)},
+		"X-Amz-Date":          {now.Format("20060102T150405Z")},
+		"X-Amz-Expires":       {strconv.Itoa(int(ttl.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		u.RawQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+	u.RawQuery += "&X-Amz-Signature=" + s.signature(now, canonical)
+	return u.String(), nil
+}
+
+// putObject 使用单个请求上传文件
+func (s *S3) putObject(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.send(ctx, http.MethodPut, key, nil, header, r, size, s3UnsignedPayload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// multipartUpload 分片上传，first为已读取的第一个完整分片，失败时取消上传以释放已上传的分片
+func (s *S3) multipartUpload(ctx context.Context, key string, first []byte, r io.Reader, contentType string) (err error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := s.doXML(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, &initiated); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			// 使用不随请求取消的上下文，确保取消操作能够完成
+			if resp, abortErr := s.do(context.WithoutCancel(ctx), http.MethodDelete, key, url.Values{"uploadId": {initiated.UploadID}}, nil, nil); abortErr == nil {
+				resp.Body.Close()
+			}
+		}
+	}()
+
+	type completedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	var parts []completedPart
+
+	data := first
+	for number := 1; ; number++ {
+		if number > s3MaxParts {
+			return fmt.Errorf("文件超过S3分片数量上限，请调大分片大小")
+		}
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {initiated.UploadID}}
+		sum := sha256.Sum256(data)
+		resp, err := s.send(ctx, http.MethodPut, key, query, nil, bytes.NewReader(data), int64(len(data)), hex.EncodeToString(sum[:]))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+
+		n, err := io.ReadFull(r, first)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		data = first[:n]
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	// 完成分片上传失败时S3可能返回200并在响应体中给出错误
+	var completed struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	sum := sha256.Sum256(body)
+	resp, err := s.send(ctx, http.MethodPost, key, url.Values{"uploadId": {initiated.UploadID}}, nil, bytes.NewReader(body), int64(len(body)), hex.EncodeToString(sum[:]))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := xml.NewDecoder(resp.Body).Decode(&completed); err != nil {
+		return fmt.Errorf("解析S3响应失败: %w", err)
+	}
+	if completed.XMLName.Local == "Error" {
+		return fmt.Errorf("S3完成分片上传失败: %s: %s", completed.Code, completed.Message)
+	}
+	return nil
+}
+
+// doXML 发送无请求体的请求并将XML响应解码到out
+func (s *S3) doXML(ctx context.Context, method, key string, query url.Values, header http.Header, out interface{}) error {
+	resp, err := s.do(ctx, method, key, query, header, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := xml.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析S3响应失败: %w", err)
+	}
+	return nil
+}
+
+// do 发送无请求体的请求
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader) (*http.Response, error) {
+	return s.send(ctx, method, key, query, header, body, 0, s3EmptyHash)
+}
+
+// send 签名并发送请求，非2xx响应转换为错误，404转换为ErrNotFound
+func (s *S3) send(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	u := s.objectURL(key)
+	u.RawQuery = canonicalQuery(query)
+
+	if body == nil || size == 0 {
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	s.sign(req, payloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	xml.Unmarshal(data, &s3Err)
+	return nil, fmt.Errorf("S3 %s %s 返回 %d: %s %s", method, key, resp.StatusCode, s3Err.Code, s3Err.Message)
+}
+
+// sign 为请求添加SigV4签名头
+func (s *S3) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKey, s.scope(now), signedHeaders, s.signature(now, canonical)))
+}
+
+// signature 计算规范请求的签名
+func (s *S3) signature(t time.Time, canonicalRequest string) string {
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := s3Algorithm + "\n" + t.Format("20060102T150405Z") + "\n" + s.scope(t) + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// scope 返回签名的凭证范围
+func (s *S3) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+// objectURL 返回对象地址，key为空时返回存储桶地址
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	prefix := strings.TrimRight(u.Path, "/")
+	if s.config.PathStyle {
+		prefix += "/" + s.config.Bucket
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+	}
+
+	u.Path = prefix + "/" + key
+	u.RawPath = uriEncode(prefix, false) + "/" + uriEncode(key, false)
+	return &u
+}
+
+// canonicalQuery 按SigV4规则编码并排序查询参数
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode 按SigV4规则编码，只保留字母、数字和 -_.~，encodeSlash为false时保留 /
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
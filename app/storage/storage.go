@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+// 定义错误
+var (
+	ErrNotFound     = errors.New("文件不存在")
+	ErrInvalidKey   = errors.New("无效的文件路径")
+	ErrNotSupported = errors.New("存储驱动不支持该操作")
+)
+
+// Object 存储对象的元数据
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ETag        string
+	ModTime     time.Time
+}
+
+// Storage 文件存储接口，键为以 / 分隔的相对路径
+type Storage interface {
+	// Put 写入文件，size为-1表示长度未知，已存在的文件会被覆盖
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取文件，不存在时返回ErrNotFound，调用方负责关闭
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetRange 读取文件中从offset开始的length个字节，用于断点续传和分段下载
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// Stat 获取文件元数据，不存在时返回ErrNotFound
+	Stat(ctx context.Context, key string) (*Object, error)
+	// List 列出键以prefix开头的所有文件
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// SignedURL 返回有效期为ttl的临时访问地址，不支持时返回ErrNotSupported
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// validateKey 拒绝空键、绝对路径和包含 . 或 .. 的键
+func validateKey(key string) error {
+	if key == "" || path.Clean("/" + key)[1:] != key {
+		return fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// 默认的重试次数和重试间隔
+const (
+	defaultAttempts = 3
+	retryBackoff    = 20 * time.Millisecond
+)
+
+// MySQL中表示事务冲突的错误码
+const (
+	mysqlDeadlock        = 1213 // ER_LOCK_DEADLOCK
+	mysqlLockWaitTimeout = 1205 // ER_LOCK_WAIT_TIMEOUT
+)
+
+// ctxKey 上下文中保存事务的键
+type ctxKey struct{}
+
+// state 一层事务或保存点的状态
+type state struct {
+	tx          *gorm.DB
+	afterCommit []func(ctx context.Context)
+}
+
+// options 事务选项
+type options struct {
+	attempts int
+	txOpts   *sql.TxOptions
+}
+
+// Option 事务选项
+type Option func(*options)
+
+// Attempts 设置遇到死锁或序列化失败时的最多执行次数，默认为3
+func Attempts(n int) Option {
+	return func(o *options) {
+		o.attempts = max(n, 1)
+	}
+}
+
+// Isolation 设置事务的隔离级别，只对最外层事务有效
+func Isolation(level sql.IsolationLevel) Option {
+	return func(o *options) {
+		o.txOpts = &sql.TxOptions{Isolation: level}
+	}
+}
+
+// Run 在事务中执行fn，fn中通过DB(ctx, db)获取的连接都使用该事务，fn返回错误时回滚
+// 上下文中已有事务时创建保存点，fn失败只回滚到保存点，是否提交由外层事务决定
+// 最外层事务遇到死锁或序列化失败时整体重试，fn可能被执行多次，不应包含事务之外的副作用，这类操作使用AfterCommit
+func Run(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error, opts ...Option) error {
+	if parent, ok := ctx.Value(ctxKey{}).(*state); ok {
+		// GORM在已开启的事务上调用Transaction时使用保存点
+		child := &state{}
+		err := parent.tx.Transaction(func(tx *gorm.DB) error {
+			child.tx = tx
+			return fn(context.WithValue(ctx, ctxKey{}, child))
+		})
+		if err == nil {
+			parent.afterCommit = append(parent.afterCommit, child.afterCommit...)
+		}
+		return err
+	}
+
+	o := options{attempts: defaultAttempts}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var err error
+	for attempt := 1; attempt <= o.attempts; attempt++ {
+		root := &state{}
+		err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			root.tx = tx
+			return fn(context.WithValue(ctx, ctxKey{}, root))
+		}, o.txOpts)
+		if err == nil {
+			for _, callback := range root.afterCommit {
+				callback(ctx)
+			}
+			return nil
+		}
+		if !Retryable(err) || attempt == o.attempts {
+			break
+		}
+
+		log.Printf("事务冲突，第%d次重试: %v", attempt, err)
+		wait := retryBackoff*time.Duration(attempt) + rand.N(retryBackoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+	return err
+}
+
+// DB 返回上下文中的事务，不在事务中时返回db，均已绑定上下文
+func DB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if s, ok := ctx.Value(ctxKey{}).(*state); ok {
+		return s.tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// Active 上下文中是否有事务
+func Active(ctx context.Context) bool {
+	_, ok := ctx.Value(ctxKey{}).(*state)
+	return ok
+}
+
+// AfterCommit 注册在最外层事务提交后执行的函数，如发送通知、写入外部系统等
+// 所在的事务或保存点回滚时不会执行，不在事务中时立即执行；fn收到的上下文不包含事务
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if s, ok := ctx.Value(ctxKey{}).(*state); ok {
+		s.afterCommit = append(s.afterCommit, fn)
+		return
+	}
+	fn(ctx)
+}
+
+// Retryable 错误是否由死锁、锁等待超时或序列化失败引起，重新执行整个事务可能成功
+func Retryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDeadlock || mysqlErr.Number == mysqlLockWaitTimeout || string(mysqlErr.SQLState[:]) == "40001"
+	}
+	// 其他数据库驱动通过SQLState返回错误状态，40001为序列化失败，40P01为PostgreSQL的死锁
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		code := stateErr.SQLState()
+		return code == "40001" || code == "40P01"
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"context"
+	"log"
+	"maps"
+	"slices"
+
+	"github.com/NextEraAbyss/fiber-template/app/cache"
+	"github.com/NextEraAbyss/fiber-template/app/transaction"
+	"gorm.io/gorm"
+)
+
+var Cache *cache.Cache
+
+// cachedTables 写入时需要失效缓存的表
+// 缓存这些表的数据时应使用表名作为缓存标签
+var cachedTables = map[string]bool{
+	"users": true,
+}
+
+// InitCache 初始化缓存
+// Redis不可用时降级为进程内缓存，避免缓存故障导致应用无法启动
+func InitCache(config *Config) {
+	var driver cache.Driver
+	switch config.Cache.Driver {
+	case "redis":
+		if err := InitRedis(config); err != nil {
+			log.Printf("无法连接到Redis，缓存降级为内存驱动: %v", err)
+			driver = cache.NewMemoryDriver(config.Cache.MaxEntries)
+			break
+		}
+		driver = cache.NewRedisDriver(GetRedis())
+	case "memory":
+		driver = cache.NewMemoryDriver(config.Cache.MaxEntries)
+	default:
+		log.Fatalf("不支持的缓存驱动: %s", config.Cache.Driver)
+	}
+
+	Cache = cache.New(driver, config.Cache.Prefix, config.Cache.TTL, config.Cache.Tags)
+	if config.Cache.Tags {
+		registerCacheInvalidation(GetDB())
+	} else {
+		log.Printf("警告: 缓存标签未启用，写入 %v 后不会失效相关缓存，缓存的数据在过期前可能是旧值", slices.Sorted(maps.Keys(cachedTables)))
+	}
+	log.Printf("缓存初始化成功: %s", config.Cache.Driver)
+}
+
+// registerCacheInvalidation 注册GORM回调，在写入cachedTables中的表后失效以表名为标签的缓存
+// 通过transaction.Run开启的事务中的写入在提交后才失效，避免并发请求在提交前把旧数据重新写入缓存
+func registerCacheInvalidation(db *gorm.DB) {
+	flush := func(ctx context.Context, table string) {
+		if err := Cache.FlushTags(ctx, table); err != nil {
+			log.Printf("失效缓存失败: %s: %v", table, err)
+		}
+	}
+	invalidate := func(tx *gorm.DB) {
+		table := tx.Statement.Table
+		if tx.Error != nil || tx.RowsAffected == 0 || !cachedTables[table] {
+			return
+		}
+		ctx := tx.Statement.Context
+		if transaction.Active(ctx) {
+			transaction.AfterCommit(ctx, func(ctx context.Context) {
+				flush(ctx, table)
+			})
+			return
+		}
+		flush(ctx, table)
+	}
+
+	db.Callback().Create().After("gorm:create").Register("cache:invalidate", invalidate)
+	db.Callback().Update().After("gorm:update").Register("cache:invalidate", invalidate)
+	db.Callback().Delete().After("gorm:delete").Register("cache:invalidate", invalidate)
+}
+
+// GetCache 返回缓存实例
+func GetCache() cache.Store {
+	return Cache
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -23,6 +24,12 @@ type Config struct {
 		Host       string
 		APIPrefix  string
 		APITimeout time.Duration
+		BodyLimit  int // 请求体大小限制，文件上传不受此限制，由Upload中的配置限制
+
+		// 反向代理配置，只有来自TrustedProxies的请求才使用ProxyHeader中的客户端IP
+		// 代理应覆盖而不是追加该请求头，X-Forwarded-For最左侧的地址可由客户端伪造
+		TrustedProxies []string
+		ProxyHeader    string
 	}
 
 	// 数据库配置
@@ -101,10 +108,11 @@ type Config struct {
 
 	// 缓存配置
 	Cache struct {
-		Driver string
-		Prefix string
-		TTL    time.Duration
-		Tags   bool
+		Driver     string
+		Prefix     string
+		TTL        time.Duration
+		Tags       bool
+		MaxEntries int
 	}
 
 	// 邮件配置
@@ -118,6 +126,9 @@ type Config struct {
 		FromAddress string
 		FromName    string
 		LogChannel  string
+		Path        string        // file驱动保存.eml文件的目录
+		Timeout     time.Duration // SMTP连接和发送的超时时间
+		Queue       bool          // 是否通过后台队列异步发送
 	}
 
 	// 文件上传配置
@@ -127,18 +138,67 @@ type Config struct {
 		AllowedTypes   []string
 		Path           string
 		PublicPath     string
-		TempPath       string
+		TempPath       string // 上传临时目录，分片上传的内容只保存在创建会话的节点上，多节点部署时需要会话保持
 		MaxFiles       int
 		ImageMaxWidth  int
 		ImageMaxHeight int
 		ImageQuality   int
+
+		// 分片上传配置
+		ResumableMaxSize int64         // 分片上传的单个文件大小限制
+		SessionTTL       time.Duration // 分片上传会话的有效期，每次上传分片后重新计算
+		MaxSessions      int           // 每个用户未完成的分片上传数量上限
+
+		// 下载链接签名配置
+		SigningKey      string        // 下载链接的HMAC签名密钥，未配置时使用JWT密钥
+		SignedURLTTL    time.Duration // 下载链接的默认有效期
+		SignedURLMaxTTL time.Duration // 下载链接的最长有效期
+
+		// S3兼容对象存储配置，Driver为s3时使用
+		S3 struct {
+			Endpoint  string
+			Region    string
+			Bucket    string
+			AccessKey string
+			SecretKey string
+			PathStyle bool
+			PartSize  int64
+		}
+	}
+
+	// 队列配置
+	Queue struct {
+		Driver       string
+		Workers      int
+		Queues       []string
+		PollInterval time.Duration
+		MaxAttempts  int
+		Backoff      time.Duration
+		MaxBackoff   time.Duration
+		Timeout      time.Duration
+		DrainTimeout time.Duration
+	}
+
+	// 定时任务配置
+	Schedule struct {
+		NodeID       string
+		LockDriver   string
+		RunRetention time.Duration // 执行记录的保留时长，0表示不清理
+	}
+
+	// OpenID Connect登录配置
+	OIDC struct {
+		Providers    []OIDCProviderConfig
+		StateTTL     time.Duration // 登录请求（state、nonce和PKCE校验码）的有效期
+		SuccessURL   string        // 登录完成后跳转的前端地址，令牌以URL片段附加，为空时回调直接返回JSON
+		AutoRegister bool          // 邮箱未注册时是否自动创建用户
 	}
 
 	// 安全配置
 	Security struct {
 		BcryptCost               int
 		PasswordMinLength        int
-		PasswordMaxLength        int
+		PasswordMaxLength        int // 按字符计，另外不能超过PasswordMaxBytes个字节
 		PasswordRequireNumbers   bool
 		PasswordRequireSymbols   bool
 		PasswordRequireUppercase bool
@@ -154,9 +214,32 @@ type Config struct {
 		CookieSecure             bool
 		CookieHTTPOnly           bool
 		CookieSameSite           string
+		PasswordResetTTL         time.Duration // 重置密码链接的有效期
+		PasswordResetURL         string        // 重置密码页面地址，令牌以token参数附加
+		EmailVerifyTTL           time.Duration // 邮箱验证链接的有效期
+		EmailVerifyURL           string        // 邮箱验证页面地址，令牌以token参数附加
+		EmailVerifyResend        time.Duration // 重新发送验证邮件的最小间隔
+		LoginMaxAttempts         int           // 账号连续登录失败多少次后锁定
+		LoginLockoutDuration     time.Duration // 首次锁定时长，之后每次锁定翻倍
+		LoginLockoutMaxDuration  time.Duration // 锁定时长上限
+		LoginIPMaxAttempts       int           // 同一IP在窗口期内允许的登录失败次数
+		LoginIPWindow            time.Duration // IP登录失败计数的窗口期
+		TwoFactorChallengeTTL    time.Duration // 两步验证中间令牌的有效期
+		TwoFactorRequiredAdmin   bool          // 管理员是否必须启用两步验证
+		APIKeyMaxPerUser         int           // 每个用户最多可创建的API密钥数量
 	}
 }
 
+// OIDCProviderConfig OpenID Connect身份提供方配置
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
 var config *Config
 
 // Load 返回应用程序配置
@@ -190,6 +273,12 @@ func Load() *Config {
 	loadMailConfig(config)
 	// 加载文件上传配置
 	loadUploadConfig(config)
+	// 加载队列配置
+	loadQueueConfig(config)
+	// 加载定时任务配置
+	loadScheduleConfig(config)
+	// 加载OpenID Connect登录配置
+	loadOIDCConfig(config)
 	// 加载安全配置
 	loadSecurityConfig(config)
 
@@ -279,6 +368,9 @@ func loadAppConfig(c *Config) {
 	c.App.Host = getEnv("HOST", "0.0.0.0")
 	c.App.APIPrefix = getEnv("API_PREFIX", "/api/v1")
 	c.App.APITimeout = getEnvDuration("API_TIMEOUT", 30*time.Second)
+	c.App.BodyLimit = int(getEnvSize("BODY_LIMIT", 4*1024*1024)) // 4MB
+	c.App.TrustedProxies = getEnvSlice("TRUSTED_PROXIES", []string{})
+	c.App.ProxyHeader = getEnv("PROXY_HEADER", "X-Real-IP")
 }
 
 // 加载数据库配置
@@ -339,7 +431,7 @@ func loadLogConfig(c *Config) {
 func loadCORSConfig(c *Config) {
 	c.CORS.AllowOrigins = getEnvSlice("CORS_ALLOW_ORIGINS", []string{"http://localhost:3000", "http://localhost:8080"})
 	c.CORS.AllowMethods = getEnvSlice("CORS_ALLOW_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"})
-	c.CORS.AllowHeaders = getEnvSlice("CORS_ALLOW_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"})
+	c.CORS.AllowHeaders = getEnvSlice("CORS_ALLOW_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-API-Key"})
 	c.CORS.ExposeHeaders = getEnvSlice("CORS_EXPOSE_HEADERS", []string{"Content-Length", "Content-Range"})
 	c.CORS.MaxAge = getEnvInt("CORS_MAX_AGE", 86400)
 	c.CORS.AllowCredentials = getEnvBool("CORS_ALLOW_CREDENTIALS", true)
@@ -361,6 +453,7 @@ func loadCacheConfig(c *Config) {
 	c.Cache.Prefix = getEnv("CACHE_PREFIX", "fiber_template")
 	c.Cache.TTL = getEnvDuration("CACHE_TTL", time.Hour)
 	c.Cache.Tags = getEnvBool("CACHE_TAGS", true)
+	c.Cache.MaxEntries = getEnvInt("CACHE_MAX_ENTRIES", 10000)
 }
 
 // 加载邮件配置
@@ -374,12 +467,15 @@ func loadMailConfig(c *Config) {
 	c.Mail.FromAddress = getEnv("MAIL_FROM_ADDRESS", "noreply@yourdomain.com")
 	c.Mail.FromName = getEnv("MAIL_FROM_NAME", c.App.Name)
 	c.Mail.LogChannel = getEnv("MAIL_LOG_CHANNEL", "stack")
+	c.Mail.Path = getEnv("MAIL_FILE_PATH", "./storage/mail")
+	c.Mail.Timeout = getEnvDuration("MAIL_TIMEOUT", 30*time.Second)
+	c.Mail.Queue = getEnvBool("MAIL_QUEUE", false)
 }
 
 // 加载文件上传配置
 func loadUploadConfig(c *Config) {
 	c.Upload.Driver = getEnv("UPLOAD_DRIVER", "local")
-	c.Upload.MaxSize = getEnvInt64("UPLOAD_MAX_SIZE", 10*1024*1024) // 10MB
+	c.Upload.MaxSize = getEnvSize("UPLOAD_MAX_SIZE", 10*1024*1024) // 10MB
 	c.Upload.AllowedTypes = getEnvSlice("UPLOAD_ALLOWED_TYPES", []string{"image/jpeg", "image/png", "image/gif", "application/pdf"})
 	c.Upload.Path = getEnv("UPLOAD_PATH", "./storage/uploads")
 	c.Upload.PublicPath = getEnv("UPLOAD_PUBLIC_PATH", "./public/uploads")
@@ -388,13 +484,71 @@ func loadUploadConfig(c *Config) {
 	c.Upload.ImageMaxWidth = getEnvInt("UPLOAD_IMAGE_MAX_WIDTH", 2000)
 	c.Upload.ImageMaxHeight = getEnvInt("UPLOAD_IMAGE_MAX_HEIGHT", 2000)
 	c.Upload.ImageQuality = getEnvInt("UPLOAD_IMAGE_QUALITY", 85)
+	c.Upload.ResumableMaxSize = getEnvSize("UPLOAD_RESUMABLE_MAX_SIZE", 2*1024*1024*1024) // 2GB
+	c.Upload.SessionTTL = getEnvDuration("UPLOAD_SESSION_TTL", 24*time.Hour)
+	c.Upload.MaxSessions = getEnvInt("UPLOAD_MAX_SESSIONS", 10)
+	c.Upload.SigningKey = getEnv("UPLOAD_SIGNING_KEY", c.JWT.Secret)
+	c.Upload.SignedURLTTL = getEnvDuration("UPLOAD_SIGNED_URL_TTL", 15*time.Minute)
+	c.Upload.SignedURLMaxTTL = getEnvDuration("UPLOAD_SIGNED_URL_MAX_TTL", 7*24*time.Hour)
+	c.Upload.S3.Endpoint = getEnv("S3_ENDPOINT", "")
+	c.Upload.S3.Region = getEnv("S3_REGION", "us-east-1")
+	c.Upload.S3.Bucket = getEnv("S3_BUCKET", "")
+	c.Upload.S3.AccessKey = getEnv("S3_ACCESS_KEY_ID", "")
+	c.Upload.S3.SecretKey = getEnv("S3_SECRET_ACCESS_KEY", "")
+	c.Upload.S3.PathStyle = getEnvBool("S3_USE_PATH_STYLE", false)
+	c.Upload.S3.PartSize = getEnvSize("S3_PART_SIZE", 16*1024*1024) // 16MB
+}
+
+// 加载队列配置
+func loadQueueConfig(c *Config) {
+	c.Queue.Driver = getEnv("QUEUE_DRIVER", "database")
+	c.Queue.Workers = getEnvInt("QUEUE_WORKERS", 4)
+	c.Queue.Queues = getEnvSlice("QUEUE_NAMES", []string{"default"})
+	c.Queue.PollInterval = getEnvDuration("QUEUE_POLL_INTERVAL", time.Second)
+	c.Queue.MaxAttempts = getEnvInt("QUEUE_MAX_ATTEMPTS", 3)
+	c.Queue.Backoff = getEnvDuration("QUEUE_BACKOFF", 10*time.Second)
+	c.Queue.MaxBackoff = getEnvDuration("QUEUE_MAX_BACKOFF", 10*time.Minute)
+	c.Queue.Timeout = getEnvDuration("QUEUE_TIMEOUT", 5*time.Minute)
+	c.Queue.DrainTimeout = getEnvDuration("QUEUE_DRAIN_TIMEOUT", 30*time.Second)
+}
+
+// 加载定时任务配置
+func loadScheduleConfig(c *Config) {
+	hostname, _ := os.Hostname()
+	c.Schedule.NodeID = getEnv("SCHEDULE_NODE_ID", fmt.Sprintf("%s-%d", hostname, os.Getpid()))
+	c.Schedule.LockDriver = getEnv("SCHEDULE_LOCK_DRIVER", "database")
+	c.Schedule.RunRetention = getEnvDuration("SCHEDULE_RUN_RETENTION", 30*24*time.Hour)
+}
+
+// 加载OpenID Connect登录配置
+// OIDC_PROVIDERS 为逗号分隔的提供方名称，每个提供方通过 OIDC_<名称>_ISSUER 等变量配置
+func loadOIDCConfig(c *Config) {
+	c.OIDC.StateTTL = getEnvDuration("OIDC_STATE_TTL", 10*time.Minute)
+	c.OIDC.SuccessURL = getEnv("OIDC_SUCCESS_URL", "")
+	c.OIDC.AutoRegister = getEnvBool("OIDC_AUTO_REGISTER", true)
+
+	for _, name := range getEnvSlice("OIDC_PROVIDERS", nil) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		c.OIDC.Providers = append(c.OIDC.Providers, OIDCProviderConfig{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", strings.TrimRight(c.App.URL, "/")+"/api/v1/auth/oidc/"+name+"/callback"),
+			Scopes:       getEnvSlice(prefix+"SCOPES", []string{"openid", "email", "profile"}),
+		})
+	}
 }
 
 // 加载安全配置
 func loadSecurityConfig(c *Config) {
 	c.Security.BcryptCost = getEnvInt("BCRYPT_COST", 10)
 	c.Security.PasswordMinLength = getEnvInt("PASSWORD_MIN_LENGTH", 8)
-	c.Security.PasswordMaxLength = getEnvInt("PASSWORD_MAX_LENGTH", 100)
+	c.Security.PasswordMaxLength = getEnvInt("PASSWORD_MAX_LENGTH", PasswordMaxBytes)
 	c.Security.PasswordRequireNumbers = getEnvBool("PASSWORD_REQUIRE_NUMBERS", true)
 	c.Security.PasswordRequireSymbols = getEnvBool("PASSWORD_REQUIRE_SYMBOLS", true)
 	c.Security.PasswordRequireUppercase = getEnvBool("PASSWORD_REQUIRE_UPPERCASE", true)
@@ -410,6 +564,19 @@ func loadSecurityConfig(c *Config) {
 	c.Security.CookieSecure = getEnvBool("COOKIE_SECURE", true)
 	c.Security.CookieHTTPOnly = getEnvBool("COOKIE_HTTP_ONLY", true)
 	c.Security.CookieSameSite = getEnv("COOKIE_SAME_SITE", "strict")
+	c.Security.PasswordResetTTL = getEnvDuration("PASSWORD_RESET_TTL", time.Hour)
+	c.Security.PasswordResetURL = getEnv("PASSWORD_RESET_URL", strings.TrimRight(c.App.URL, "/")+"/reset-password")
+	c.Security.EmailVerifyTTL = getEnvDuration("EMAIL_VERIFY_TTL", 24*time.Hour)
+	c.Security.EmailVerifyURL = getEnv("EMAIL_VERIFY_URL", strings.TrimRight(c.App.URL, "/")+"/verify-email")
+	c.Security.EmailVerifyResend = getEnvDuration("EMAIL_VERIFY_RESEND_INTERVAL", time.Minute)
+	c.Security.LoginMaxAttempts = getEnvInt("LOGIN_MAX_ATTEMPTS", 5)
+	c.Security.LoginLockoutDuration = getEnvDuration("LOGIN_LOCKOUT_DURATION", time.Minute)
+	c.Security.LoginLockoutMaxDuration = getEnvDuration("LOGIN_LOCKOUT_MAX_DURATION", 24*time.Hour)
+	c.Security.LoginIPMaxAttempts = getEnvInt("LOGIN_IP_MAX_ATTEMPTS", 20)
+	c.Security.LoginIPWindow = getEnvDuration("LOGIN_IP_WINDOW", 15*time.Minute)
+	c.Security.TwoFactorChallengeTTL = getEnvDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute)
+	c.Security.TwoFactorRequiredAdmin = getEnvBool("TWO_FACTOR_REQUIRED_ADMIN", true)
+	c.Security.APIKeyMaxPerUser = getEnvInt("API_KEY_MAX_PER_USER", 20)
 }
 
 // 辅助函数：获取环境变量或返回默认值
@@ -442,15 +609,30 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
-// 辅助函数：获取int64类型环境变量
-func getEnvInt64(key string, defaultValue int64) int64 {
-	if value, exists := os.LookupEnv(key); exists {
-		i, err := strconv.ParseInt(value, 10, 64)
-		if err == nil {
-			return i
+// 辅助函数：获取字节大小类型环境变量，支持KB、MB、GB后缀（按1024换算），如 10MB
+func getEnvSize(key string, defaultValue int64) int64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	value = strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, multiplier = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), unit.size
+			break
 		}
 	}
-	return defaultValue
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return defaultValue
+	}
+	return n * multiplier
 }
 
 // 辅助函数：获取时间持续时间类型环境变量
@@ -25,7 +25,8 @@ func InitDB(config *Config) {
 	}
 
 	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logLevel),
+		Logger:         logger.Default.LogMode(logLevel),
+		TranslateError: true, // 将唯一约束冲突等错误转换为gorm.ErrDuplicatedKey
 	})
 
 	if err != nil {
@@ -15,20 +15,34 @@ type JWTConfig struct {
 
 // JWTClaims 自定义JWT声明
 type JWTClaims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID       uint   `json:"user_id"`
+	Email        string `json:"email"`
+	TokenVersion int    `json:"ver"`               // 与用户的TokenVersion不一致时令牌失效
+	Purpose      string `json:"purpose,omitempty"` // 非空表示只能用于特定步骤的临时令牌，不能用于访问API
 	jwt.RegisteredClaims
 }
 
 // GenerateToken 生成JWT令牌
-func GenerateToken(userID uint, email string, config *Config) (string, error) {
+func GenerateToken(userID uint, email string, tokenVersion int, config *Config) (string, error) {
+	return generateToken(userID, email, tokenVersion, "", time.Duration(30)*time.Minute, config)
+}
+
+// GeneratePurposeToken 生成只能用于指定用途的短期令牌，如两步验证的中间令牌
+func GeneratePurposeToken(userID uint, email string, tokenVersion int, purpose string, ttl time.Duration, config *Config) (string, error) {
+	return generateToken(userID, email, tokenVersion, purpose, ttl, config)
+}
+
+// generateToken 生成并签名JWT令牌
+func generateToken(userID uint, email string, tokenVersion int, purpose string, ttl time.Duration, config *Config) (string, error) {
 	// 设置过期时间
-	expirationTime := time.Now().Add(time.Duration(30) * time.Minute)
+	expirationTime := time.Now().Add(ttl)
 
 	// 创建声明
 	claims := &JWTClaims{
-		UserID: userID,
-		Email:  email,
+		UserID:       userID,
+		Email:        email,
+		TokenVersion: tokenVersion,
+		Purpose:      purpose,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"log"
+	"os"
+
+	"github.com/NextEraAbyss/fiber-template/app/mail"
+)
+
+var (
+	Mailer       mail.Mailer
+	MailRenderer *mail.Renderer
+)
+
+// InitMailer 初始化邮件发送器，需在队列初始化之后调用
+func InitMailer(config *Config) {
+	from := mail.Address{
+		Name:    config.Mail.FromName,
+		Address: config.Mail.FromAddress,
+	}
+
+	var mailer mail.Mailer
+	switch config.Mail.Mailer {
+	case "smtp":
+		smtpMailer, err := mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:       config.Mail.Host,
+			Port:       config.Mail.Port,
+			Username:   config.Mail.Username,
+			Password:   config.Mail.Password,
+			Encryption: config.Mail.Encryption,
+			Timeout:    config.Mail.Timeout,
+			From:       from,
+		})
+		if err != nil {
+			log.Fatalf("无法创建SMTP邮件驱动: %v", err)
+		}
+		mailer = smtpMailer
+	case "log":
+		mailer = mail.NewLogMailer(log.New(os.Stdout, "", log.LstdFlags), from)
+	case "file":
+		fileMailer, err := mail.NewFileMailer(config.Mail.Path, from)
+		if err != nil {
+			log.Fatalf("无法创建邮件目录: %v", err)
+		}
+		mailer = fileMailer
+	default:
+		log.Fatalf("不支持的邮件驱动: %s", config.Mail.Mailer)
+	}
+
+	if config.Mail.Queue {
+		mailer = mail.NewQueuedMailer(mailer, GetQueue())
+	}
+	Mailer = mailer
+
+	renderer, err := mail.NewRenderer(mail.DefaultTemplates(), mail.App{
+		Name: config.App.Name,
+		URL:  config.App.URL,
+	}, config.App.Locale)
+	if err != nil {
+		log.Fatalf("无法加载邮件模板: %v", err)
+	}
+	MailRenderer = renderer
+
+	log.Printf("邮件发送器初始化成功: %s", config.Mail.Mailer)
+}
+
+// GetMailer 返回邮件发送器实例
+func GetMailer() mail.Mailer {
+	return Mailer
+}
+
+// GetMailRenderer 返回邮件模板渲染器实例
+func GetMailRenderer() *mail.Renderer {
+	return MailRenderer
+}
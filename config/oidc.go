@@ -0,0 +1,36 @@
+package config
+
+import (
+	"log"
+
+	"github.com/NextEraAbyss/fiber-template/app/oidc"
+)
+
+var OIDCProviders map[string]*oidc.Provider
+
+// InitOIDC 初始化OpenID Connect身份提供方，发现文档在首次登录时获取
+func InitOIDC(config *Config) {
+	OIDCProviders = make(map[string]*oidc.Provider, len(config.OIDC.Providers))
+	for _, p := range config.OIDC.Providers {
+		if p.Issuer == "" || p.ClientID == "" {
+			log.Fatalf("OIDC提供方 %s 缺少ISSUER或CLIENT_ID配置", p.Name)
+		}
+		OIDCProviders[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, nil)
+	}
+	if len(OIDCProviders) > 0 {
+		log.Printf("OIDC登录已启用: %d 个提供方", len(OIDCProviders))
+	}
+}
+
+// GetOIDCProvider 返回指定名称的身份提供方
+func GetOIDCProvider(name string) (*oidc.Provider, bool) {
+	provider, ok := OIDCProviders[name]
+	return provider, ok
+}
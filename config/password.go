@@ -1,6 +1,11 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -15,3 +20,46 @@ func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// PasswordMaxBytes bcrypt只使用密码的前72个字节，更长的密码无法哈希
+const PasswordMaxBytes = 72
+
+// ValidatePassword 按安全配置检查密码强度，返回第一条不满足的规则
+func ValidatePassword(password string, config *Config) error {
+	length := utf8.RuneCountInString(password)
+	if length < config.Security.PasswordMinLength {
+		return fmt.Errorf("密码长度不能少于%d个字符", config.Security.PasswordMinLength)
+	}
+	if length > config.Security.PasswordMaxLength {
+		return fmt.Errorf("密码长度不能超过%d个字符", config.Security.PasswordMaxLength)
+	}
+	if len(password) > PasswordMaxBytes {
+		return fmt.Errorf("密码长度不能超过%d个字节", PasswordMaxBytes)
+	}
+
+	var hasNumber, hasSymbol, hasUpper, hasLower bool
+	for _, r := range password {
+		switch {
+		case unicode.IsDigit(r):
+			hasNumber = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	switch {
+	case config.Security.PasswordRequireNumbers && !hasNumber:
+		return errors.New("密码必须包含数字")
+	case config.Security.PasswordRequireSymbols && !hasSymbol:
+		return errors.New("密码必须包含特殊字符")
+	case config.Security.PasswordRequireUppercase && !hasUpper:
+		return errors.New("密码必须包含大写字母")
+	case config.Security.PasswordRequireLowercase && !hasLower:
+		return errors.New("密码必须包含小写字母")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"context"
+	"log"
+
+	"github.com/NextEraAbyss/fiber-template/app/queue"
+)
+
+var Queue *queue.Queue
+
+// InitQueue 初始化后台任务队列
+func InitQueue(config *Config) {
+	var driver queue.Driver
+	switch config.Queue.Driver {
+	case "database":
+		driver = queue.NewDBDriver(GetDB())
+	case "memory":
+		driver = queue.NewMemoryDriver()
+	default:
+		log.Fatalf("不支持的队列驱动: %s", config.Queue.Driver)
+	}
+
+	Queue = queue.New(driver, queue.Options{
+		Workers:      config.Queue.Workers,
+		Queues:       config.Queue.Queues,
+		PollInterval: config.Queue.PollInterval,
+		MaxAttempts:  config.Queue.MaxAttempts,
+		Backoff:      config.Queue.Backoff,
+		MaxBackoff:   config.Queue.MaxBackoff,
+		Timeout:      config.Queue.Timeout,
+	})
+
+	log.Printf("任务队列初始化成功: %s", config.Queue.Driver)
+}
+
+// GetQueue 返回后台任务队列实例
+func GetQueue() *queue.Queue {
+	return Queue
+}
+
+// ShutdownQueue 停止后台任务队列，等待执行中的任务完成
+func ShutdownQueue(config *Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Queue.DrainTimeout)
+	defer cancel()
+
+	if err := Queue.Shutdown(ctx); err != nil {
+		log.Printf("任务队列未能在限定时间内完成: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"context"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var Redis *redis.Client
+
+// InitRedis 初始化Redis连接，连接失败时返回错误
+func InitRedis(config *Config) error {
+	if Redis != nil {
+		return nil
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:         net.JoinHostPort(config.Redis.Host, config.Redis.Port),
+		Password:     config.Redis.Password,
+		DB:           config.Redis.DB,
+		PoolSize:     config.Redis.PoolSize,
+		MinIdleConns: config.Redis.MinIdleConns,
+		MaxRetries:   config.Redis.MaxRetries,
+		DialTimeout:  config.Redis.DialTimeout,
+		ReadTimeout:  config.Redis.ReadTimeout,
+		WriteTimeout: config.Redis.WriteTimeout,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Redis.DialTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return err
+	}
+
+	Redis = client
+	return nil
+}
+
+// GetRedis 返回Redis连接实例，未初始化时返回nil
+func GetRedis() *redis.Client {
+	return Redis
+}
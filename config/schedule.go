@@ -1,30 +1,60 @@
 package config
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/NextEraAbyss/fiber-template/app/schedule"
 )
 
 // ScheduleTask 定时任务接口
+// 除Schedule和Task外的方法均可通过嵌入*schedule.Runner获得
 type ScheduleTask interface {
-	Schedule() string // 返回cron表达式
-	Task()            // 执行任务
-	Start()           // 启动任务
-	Stop()            // 停止任务
+	Name() string                             // 返回任务名称
+	Schedule() string                         // 返回cron表达式
+	Task(ctx context.Context) error           // 执行任务，需响应ctx的取消
+	Start()                                   // 启动任务
+	Stop()                                    // 停止任务
+	Trigger() error                           // 立即执行一次任务
+	Pause(ctx context.Context) error          // 暂停任务
+	Resume(ctx context.Context) error         // 恢复任务
+	Paused(ctx context.Context) (bool, error) // 返回任务是否已暂停
+	NextRun() time.Time                       // 返回下一次计划执行的时间
+	Options() schedule.Options                // 返回任务的执行选项
 }
 
 // 所有任务实例
 var scheduleTasks []ScheduleTask
 
 // InitTasks 加载所有定时任务
-func InitTasks() error {
+func InitTasks(config *Config) error {
+	// 配置节点标识和分布式锁
+	schedule.SetNodeID(config.Schedule.NodeID)
+	switch config.Schedule.LockDriver {
+	case "database":
+		schedule.SetLocker(schedule.NewDBLocker(GetDB(), config.Schedule.NodeID))
+	case "none":
+		schedule.SetLocker(nil)
+	default:
+		// redis 等驱动尚未实现，单例任务将在每个节点执行
+		log.Printf("不支持的分布式锁驱动: %s", config.Schedule.LockDriver)
+		schedule.SetLocker(nil)
+	}
+
+	// 记录执行历史并在节点间共享暂停状态
+	schedule.SetStore(schedule.NewDBStore(GetDB()))
+
 	// 清空任务列表
 	scheduleTasks = []ScheduleTask{}
 
 	// 添加任务
 	// 在这里注册您的定时任务
-	scheduleTasks = append(scheduleTasks, schedule.NewUpdateStatistics())
+	scheduleTasks = append(scheduleTasks, schedule.NewUpdateStatistics(GetDB()))
+	scheduleTasks = append(scheduleTasks, schedule.NewCleanupUploads(GetDB(), config.Upload.TempPath, config.Upload.SessionTTL))
+	if config.Schedule.RunRetention > 0 {
+		scheduleTasks = append(scheduleTasks, schedule.NewPruneJobRuns(GetDB(), config.Schedule.RunRetention))
+	}
 
 	// 这里可以添加更多任务
 	// 例如: scheduleTasks = append(scheduleTasks, NewYourTask())
@@ -34,6 +64,21 @@ func InitTasks() error {
 	return nil
 }
 
+// GetTasks 返回所有已注册的定时任务
+func GetTasks() []ScheduleTask {
+	return scheduleTasks
+}
+
+// FindTask 按名称查找已注册的定时任务
+func FindTask(name string) (ScheduleTask, bool) {
+	for _, task := range scheduleTasks {
+		if task.Name() == name {
+			return task, true
+		}
+	}
+	return nil, false
+}
+
 // BeginTasks 启动所有定时任务
 func BeginTasks() {
 	for _, task := range scheduleTasks {
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"log"
+	"os"
+
+	"github.com/NextEraAbyss/fiber-template/app/storage"
+)
+
+var Storage storage.Storage
+
+// InitStorage 根据Upload.Driver初始化上传文件存储，并创建上传临时目录
+func InitStorage(config *Config) {
+	switch config.Upload.Driver {
+	case "local":
+		local, err := storage.NewLocal(config.Upload.Path)
+		if err != nil {
+			log.Fatalf("无法创建上传目录: %v", err)
+		}
+		Storage = local
+	case "s3":
+		s3, err := storage.NewS3(storage.S3Config{
+			Endpoint:  config.Upload.S3.Endpoint,
+			Region:    config.Upload.S3.Region,
+			Bucket:    config.Upload.S3.Bucket,
+			AccessKey: config.Upload.S3.AccessKey,
+			SecretKey: config.Upload.S3.SecretKey,
+			PathStyle: config.Upload.S3.PathStyle,
+			PartSize:  config.Upload.S3.PartSize,
+		}, nil)
+		if err != nil {
+			log.Fatalf("无法初始化S3存储: %v", err)
+		}
+		Storage = s3
+	default:
+		log.Fatalf("不支持的存储驱动: %s", config.Upload.Driver)
+	}
+
+	if err := os.MkdirAll(config.Upload.TempPath, 0o755); err != nil {
+		log.Fatalf("无法创建上传临时目录: %v", err)
+	}
+	log.Printf("文件存储初始化成功: %s", config.Upload.Driver)
+}
+
+// GetStorage 返回上传文件存储
+func GetStorage() storage.Storage {
+	return Storage
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/NextEraAbyss/fiber-template/app/model"
 	"github.com/NextEraAbyss/fiber-template/app/router"
+	"github.com/NextEraAbyss/fiber-template/app/service"
 	"github.com/NextEraAbyss/fiber-template/config"
 	_ "github.com/NextEraAbyss/fiber-template/docs" // swagger文档
 	"github.com/gofiber/fiber/v2"
@@ -37,6 +41,16 @@ func main() {
 	app := fiber.New(fiber.Config{
 		AppName:      cfg.App.Name,
 		ErrorHandler: config.ErrorHandler,
+		// 请求体以流的形式交给处理函数，文件上传时自行解析multipart并限制大小
+		// 其他接口通过parseBody读取请求体，超过BodyLimit时返回413
+		BodyLimit:                    cfg.App.BodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+		// 部署在负载均衡之后时，登录限流和审计日志需要通过代理请求头获取真实的客户端IP
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.App.TrustedProxies,
+		ProxyHeader:             cfg.App.ProxyHeader,
+		EnableIPValidation:      true,
 	})
 
 	// Swagger路由
@@ -46,7 +60,31 @@ func main() {
 	router.SetupRoutes(app)
 
 	// 启动服务器
-	log.Fatal(app.Listen(":" + cfg.App.Port))
+	go func() {
+		if err := app.Listen(":" + cfg.App.Port); err != nil {
+			log.Fatalf("服务器启动失败: %v", err)
+		}
+	}()
+
+	// 等待退出信号后优雅关闭
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	shutdown(app, cfg)
+}
+
+// shutdown 优雅关闭应用程序：停止接收请求，停止定时任务，等待队列任务完成
+func shutdown(app *fiber.App, cfg *config.Config) {
+	log.Println("正在关闭服务器...")
+
+	if err := app.ShutdownWithTimeout(cfg.App.APITimeout); err != nil {
+		log.Printf("关闭HTTP服务失败: %v", err)
+	}
+	config.EndTasks()
+	config.ShutdownQueue(cfg)
+
+	log.Println("服务器已关闭")
 }
 
 // initApp 初始化应用程序
@@ -54,14 +92,45 @@ func initApp(cfg *config.Config) {
 	// 初始化数据库连接
 	config.InitDB(cfg)
 
+	// 初始化缓存
+	config.InitCache(cfg)
+
 	// 自动迁移数据库模型
 	db := config.GetDB()
-	err := db.AutoMigrate(&model.User{})
+	err := db.AutoMigrate(
+		&model.User{},
+		&model.ScheduleLock{},
+		&model.ScheduleJob{},
+		&model.JobRun{},
+		&model.UserStatistic{},
+		&model.QueueJob{},
+		&model.UserToken{},
+		&model.AuditLog{},
+		&model.RecoveryCode{},
+		&model.APIKey{},
+		&model.UserIdentity{},
+		&model.File{},
+		&model.FileContent{},
+		&model.DownloadNonce{},
+		&model.UploadSession{},
+	)
 	if err != nil {
 		log.Fatalf("无法迁移数据库模型: %v", err)
 	}
 
+	// 初始化并启动后台任务队列，任务处理器需在启动前注册
+	config.InitQueue(cfg)
+	config.InitMailer(cfg)
+	service.RegisterJobs(config.GetQueue())
+	config.GetQueue().Start()
+
+	// 初始化OpenID Connect身份提供方
+	config.InitOIDC(cfg)
+
+	// 初始化上传文件存储
+	config.InitStorage(cfg)
+
 	// 初始化并启动定时任务
-	config.InitTasks()
+	config.InitTasks(cfg)
 	config.BeginTasks()
 }